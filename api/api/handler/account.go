@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/sunba23/news/api/negotiate"
+	"github.com/sunba23/news/internal/audit"
+	"github.com/sunba23/news/internal/database"
+)
+
+var exportMediaTypes = []string{"application/json", "application/zip"}
+
+// userExport is everything stored about a user.
+type userExport struct {
+	ExportedAt    time.Time              `json:"exported_at"`
+	Profile       profileView            `json:"profile"`
+	FavoriteTags  []database.Tag         `json:"favorite_tags"`
+	Interactions  []database.Interaction `json:"interactions"`
+	Mutes         []database.Mute        `json:"mutes"`
+	SavedSearches []savedSearchExport    `json:"saved_searches"`
+}
+
+type savedSearchExport struct {
+	database.SavedSearch
+	Matches []database.SearchMatch `json:"matches"`
+}
+
+type deletionView struct {
+	DeletionRequestedAt time.Time `json:"deletion_requested_at"`
+	PurgeAfter          time.Time `json:"purge_after"`
+}
+
+// HandleExport returns everything stored about the user, as a single JSON
+// document or as a ZIP archive with a JSON file per kind of data when the
+// client accepts application/zip.
+func (h *UserHandler) HandleExport(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Vary", "Accept")
+	format := negotiate.Best(r.Header.Get("Accept"), exportMediaTypes)
+	if format == "" {
+		http.Error(w, "Not acceptable, supported types: application/json, application/zip", http.StatusNotAcceptable)
+		return
+	}
+
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+	export, err := h.exportUser(r, user)
+	if err != nil {
+		zerolog.Ctx(r.Context()).Error().Err(err).Msg(fmt.Sprintf("exporting data of user %v failed", user.ID))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.Auditor.Record(r, audit.Event(audit.ActionExport, audit.TargetUser, user.ID))
+
+	w.Header().Set("Cache-Control", "no-store")
+	if format == "application/json" {
+		w.Header().Set("Content-Disposition", `attachment; filename="news-export.json"`)
+		writeJSON(w, r, http.StatusOK, export)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="news-export.zip"`)
+	archive := zip.NewWriter(w)
+	files := []struct {
+		name    string
+		content any
+	}{
+		{"profile.json", export.Profile},
+		{"favorite_tags.json", export.FavoriteTags},
+		{"interactions.json", export.Interactions},
+		{"mutes.json", export.Mutes},
+		{"saved_searches.json", export.SavedSearches},
+	}
+	for _, file := range files {
+		f, err := archive.CreateHeader(&zip.FileHeader{Name: file.name, Method: zip.Deflate, Modified: export.ExportedAt})
+		if err == nil {
+			encoder := json.NewEncoder(f)
+			encoder.SetIndent("", "  ")
+			err = encoder.Encode(file.content)
+		}
+		if err != nil {
+			zerolog.Ctx(r.Context()).Error().Err(err).Msg(fmt.Sprintf("writing %v of the export of user %v failed", file.name, user.ID))
+			return
+		}
+	}
+	if err := archive.Close(); err != nil {
+		zerolog.Ctx(r.Context()).Error().Err(err).Msg(fmt.Sprintf("writing the export of user %v failed", user.ID))
+	}
+}
+
+func (h *UserHandler) exportUser(r *http.Request, user *database.User) (*userExport, error) {
+	repository := *h.App.Repository()
+	export := &userExport{
+		ExportedAt: time.Now().UTC(),
+		Profile: profileView{
+			ID:          user.ID,
+			Email:       user.Email,
+			CreatedAt:   user.CreatedAt,
+			Preferences: user.Preferences,
+		},
+		SavedSearches: []savedSearchExport{},
+	}
+
+	var err error
+	if export.FavoriteTags, err = repository.GetFavoriteTags(r.Context(), user.ID); err != nil {
+		return nil, err
+	}
+	if export.Interactions, err = repository.GetInteractions(r.Context(), user.ID); err != nil {
+		return nil, err
+	}
+	if export.Mutes, err = repository.GetMutes(r.Context(), user.ID); err != nil {
+		return nil, err
+	}
+	searches, err := repository.GetSavedSearches(r.Context(), user.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, search := range searches {
+		matches, err := repository.GetSearchMatches(r.Context(), search.ID)
+		if err != nil {
+			return nil, err
+		}
+		export.SavedSearches = append(export.SavedSearches, savedSearchExport{SavedSearch: search, Matches: nonNil(matches)})
+	}
+
+	export.FavoriteTags = nonNil(export.FavoriteTags)
+	export.Interactions = nonNil(export.Interactions)
+	export.Mutes = nonNil(export.Mutes)
+	return export, nil
+}
+
+// HandleDeleteAccount schedules the user to be purged after the grace period
+// and logs them out. Every session of the user stops being valid right away;
+// logging in again before the purge keeps the account.
+func (h *UserHandler) HandleDeleteAccount(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	repository := *h.App.Repository()
+	if err := repository.RequestUserDeletion(r.Context(), user.ID); err != nil {
+		zerolog.Ctx(r.Context()).Error().Err(err).Msg(fmt.Sprintf("requesting deletion of user %v failed", user.ID))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if user, ok = h.currentUser(w, r); !ok {
+		return
+	}
+	zerolog.Ctx(r.Context()).Info().Str("user_id", user.ID).Time("deletion_requested_at", *user.DeletionRequestedAt).Msg("Account deletion requested")
+	h.Auditor.Record(r, audit.Event(audit.ActionDeletionRequest, audit.TargetUser, user.ID))
+
+	session, _ := h.SessionStore.Get(r, "news-session")
+	session.Values["authenticated"] = false
+	session.Options.MaxAge = -1
+	if err := session.Save(r, w); err != nil {
+		zerolog.Ctx(r.Context()).Error().Err(err).Msg("Session save failed")
+	}
+
+	graceDays := h.App.Config().UsersDeletionGraceDays
+	writeJSON(w, r, http.StatusAccepted, deletionView{
+		DeletionRequestedAt: *user.DeletionRequestedAt,
+		PurgeAfter:          user.DeletionRequestedAt.AddDate(0, 0, graceDays),
+	})
+}
+
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
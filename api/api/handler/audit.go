@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/sunba23/news/api/negotiate"
+	"github.com/sunba23/news/internal/audit"
+	"github.com/sunba23/news/internal/database"
+	"github.com/sunba23/news/internal/news"
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+	// auditExportPage is how many events an NDJSON export loads at a time.
+	auditExportPage = 1000
+)
+
+var auditMediaTypes = []string{"application/json", "application/x-ndjson"}
+
+type AuditHandler struct {
+	App news.App
+}
+
+// HandleGetAuditEvents lists audit events, newest first, filtered by the
+// query parameters:
+//
+//	actor             actor id
+//	action            action, or action prefix ending with *, e.g. auth.*
+//	target_type       target type, e.g. tag
+//	target_id         target id, with target_type
+//	since, until      occurred_at range, RFC 3339 timestamps or YYYY-MM-DD dates
+//	before            events older than this event id, for paging
+//	limit             number of events, 100 by default
+//
+// With Accept: application/x-ndjson every matching event is exported, one
+// JSON object per line, unless limit is set.
+func (h *AuditHandler) HandleGetAuditEvents(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Vary", "Accept")
+	format := negotiate.Best(r.Header.Get("Accept"), auditMediaTypes)
+	if format == "" {
+		http.Error(w, "Not acceptable, supported types: application/json, application/x-ndjson", http.StatusNotAcceptable)
+		return
+	}
+
+	filter, err := parseAuditFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	def := defaultAuditLimit
+	if format == "application/x-ndjson" {
+		def = 0
+	}
+	if filter.Limit, err = parseLimit(r.URL.Query(), def, maxAuditLimit); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	repository := *h.App.Repository()
+	if format == "application/json" {
+		events, err := repository.GetAuditEvents(r.Context(), filter)
+		if err != nil {
+			zerolog.Ctx(r.Context()).Error().Err(err).Msg("getting audit events has failed")
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, r, http.StatusOK, nonNil(events))
+		return
+	}
+
+	// export page by page so the whole log is never loaded at once
+	remaining := filter.Limit
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	encoder := json.NewEncoder(w)
+	for page := 0; ; page++ {
+		filter.Limit = auditExportPage
+		if remaining > 0 {
+			filter.Limit = min(remaining, auditExportPage)
+		}
+		events, err := repository.GetAuditEvents(r.Context(), filter)
+		if err != nil {
+			zerolog.Ctx(r.Context()).Error().Err(err).Msg("exporting audit events has failed")
+			if page == 0 {
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+			}
+			return
+		}
+		for _, event := range events {
+			if err := encoder.Encode(event); err != nil {
+				zerolog.Ctx(r.Context()).Error().Err(err).Msg("writing audit events has failed")
+				return
+			}
+		}
+		if len(events) < filter.Limit {
+			return
+		}
+		if remaining > 0 {
+			if remaining -= len(events); remaining == 0 {
+				return
+			}
+		}
+		filter.BeforeID = events[len(events)-1].ID
+	}
+}
+
+func parseAuditFilter(query url.Values) (database.AuditFilter, error) {
+	filter := database.AuditFilter{
+		ActorID:    query.Get("actor"),
+		Action:     query.Get("action"),
+		TargetType: query.Get("target_type"),
+		TargetID:   query.Get("target_id"),
+	}
+
+	var err error
+	if filter.Since, err = parseFilterTime(query, "since", time.UTC); err != nil {
+		return filter, err
+	}
+	if filter.Until, err = parseFilterTime(query, "until", time.UTC); err != nil {
+		return filter, err
+	}
+	if value := query.Get("before"); value != "" {
+		if filter.BeforeID, err = strconv.ParseInt(value, 10, 64); err != nil || filter.BeforeID <= 0 {
+			return filter, fmt.Errorf("invalid before %q, expected an event id", value)
+		}
+	}
+	return filter, nil
+}
+
+// adminActor names the administrator making a request on the admin listener
+// by the subject of their verified client certificate: its common name, or
+// the whole subject when it has none. ClientCertificateMiddleware makes sure
+// there is one.
+func adminActor(r *http.Request) string {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+		return audit.ActorAdmin
+	}
+	subject := r.TLS.VerifiedChains[0][0].Subject
+	name := subject.CommonName
+	if name == "" {
+		name = subject.String()
+	}
+	return audit.ActorAdmin + ":" + name
+}
@@ -9,7 +9,9 @@ import (
 	"net/http"
 
 	"github.com/gorilla/sessions"
-	"github.com/rs/zerolog/log"
+	"github.com/rs/zerolog"
+	"github.com/sunba23/news/constants"
+	"github.com/sunba23/news/internal/audit"
 	"github.com/sunba23/news/internal/database"
 	"github.com/sunba23/news/internal/news"
 	"golang.org/x/oauth2"
@@ -19,13 +21,15 @@ type AuthHandler struct {
 	App          news.App
 	Oauth        oauth2.Config
 	SessionStore *sessions.CookieStore
+	Auditor      *audit.Auditor
 }
 
-func NewAuthHandler(app news.App) *AuthHandler {
+func NewAuthHandler(app news.App, auditor *audit.Auditor) *AuthHandler {
 	authHandler := AuthHandler{
 		App:          app,
 		Oauth:        *news.OauthConfigFromConfig(*app.Config()),
 		SessionStore: sessions.NewCookieStore([]byte(app.Config().SessionSecret)),
+		Auditor:      auditor,
 	}
 	return &authHandler
 }
@@ -40,7 +44,7 @@ func (h *AuthHandler) HandleGoogleLogin(w http.ResponseWriter, r *http.Request)
 	session, _ := h.SessionStore.Get(r, "news-session")
 	session.Values["oauth_state"] = state
 	if err := session.Save(r, w); err != nil {
-		log.Error().Err(err).Msg("Session save failed")
+		zerolog.Ctx(r.Context()).Error().Err(err).Msg("Session save failed")
 	}
 
 	url := h.Oauth.AuthCodeURL(state, oauth2.AccessTypeOffline)
@@ -66,6 +70,7 @@ func (h *AuthHandler) HandleGoogleCallback(w http.ResponseWriter, r *http.Reques
 	storedState, ok := session.Values["oauth_state"].(string)
 
 	if !ok || storedState != r.URL.Query().Get("state") {
+		h.Auditor.Record(r, audit.Event(audit.ActionLoginFailed, "", ""))
 		http.Error(w, "Invalid state parameter", http.StatusBadRequest)
 		return
 	}
@@ -73,6 +78,7 @@ func (h *AuthHandler) HandleGoogleCallback(w http.ResponseWriter, r *http.Reques
 	code := r.URL.Query().Get("code")
 	token, err := h.Oauth.Exchange(context.Background(), code)
 	if err != nil {
+		h.Auditor.Record(r, audit.Event(audit.ActionLoginFailed, "", ""))
 		http.Error(w, "Token exchange failed", http.StatusInternalServerError)
 		return
 	}
@@ -87,7 +93,7 @@ func (h *AuthHandler) HandleGoogleCallback(w http.ResponseWriter, r *http.Reques
 
 	json.NewDecoder(resp.Body).Decode(&googleUser)
 
-	log.Debug().Msg(fmt.Sprintf("Successfully authenticated as %v with id %v", googleUser.Email, googleUser.ID))
+	zerolog.Ctx(r.Context()).Debug().Msg(fmt.Sprintf("Successfully authenticated as %v with id %v", googleUser.Email, googleUser.ID))
 
 	// upsert user in DB
 	user := &database.User{
@@ -96,20 +102,35 @@ func (h *AuthHandler) HandleGoogleCallback(w http.ResponseWriter, r *http.Reques
 	}
 	repository := *h.App.Repository()
 	if err := repository.UpsertUser(r.Context(), user); err != nil {
-		log.Error().Err(err).Msg("Failed to save user in database")
+		zerolog.Ctx(r.Context()).Error().Err(err).Msg("Failed to save user in database")
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
+	// logging in during the grace period keeps the account
+	if user.DeletionRequestedAt != nil {
+		if err := repository.CancelUserDeletion(r.Context(), user.ID); err != nil {
+			zerolog.Ctx(r.Context()).Error().Err(err).Msg(fmt.Sprintf("Failed to cancel deletion of user %v", user.ID))
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		zerolog.Ctx(r.Context()).Info().Str("user_id", user.ID).Msg("Account deletion cancelled")
+		h.Auditor.Record(r, database.AuditEvent{
+			ActorID: user.ID, Action: audit.ActionDeletionCancel, TargetType: audit.TargetUser, TargetID: user.ID,
+		})
+	}
 
 	session.Values["authenticated"] = true
 	session.Values["user_id"] = googleUser.ID
 	session.Values["email"] = googleUser.Email
 
 	if err := session.Save(r, w); err != nil {
-		log.Error().Err(err).Msg("Session save failed")
+		zerolog.Ctx(r.Context()).Error().Err(err).Msg("Session save failed")
 		http.Error(w, "Session save failed", http.StatusInternalServerError)
 		return
 	}
+	h.Auditor.Record(r, database.AuditEvent{
+		ActorID: user.ID, Action: audit.ActionLogin, TargetType: audit.TargetUser, TargetID: user.ID,
+	})
 
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
@@ -123,6 +144,9 @@ func (h *AuthHandler) HandleLogout(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Logout failed", http.StatusInternalServerError)
 		return
 	}
+	if uid, ok := r.Context().Value(constants.UserIdContextKey).(string); ok {
+		h.Auditor.Record(r, audit.Event(audit.ActionLogout, audit.TargetUser, uid))
+	}
 
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
@@ -0,0 +1,194 @@
+package handler
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sunba23/news/internal/database"
+)
+
+const maxFilterTags = 20
+
+// parseNewsFilter reads the GET /news query parameters:
+//
+//	tags=1,2          news tagged with any (match=any) or all (match=all) of the tags
+//	exclude_tags=3    news not tagged with any of the tags
+//	author=Gopher     exact author, case-insensitive
+//	q=go release      words all found in the title or content, case-insensitive
+//	since, until      created_at range, RFC 3339 timestamps or YYYY-MM-DD dates
+//	                  starting at midnight in location
+//	sort              newest (default), oldest or relevance
+//	fields=id,title   only load the listed fields, see parseNewsFields
+func parseNewsFilter(query url.Values, location *time.Location) (database.NewsFilter, error) {
+	var filter database.NewsFilter
+	var err error
+
+	if filter.Fields, err = parseNewsFields(query); err != nil {
+		return filter, err
+	}
+
+	if filter.TagIDs, err = parseIDList(query, "tags", maxFilterTags); err != nil {
+		return filter, err
+	}
+	if filter.ExcludeTagIDs, err = parseIDList(query, "exclude_tags", maxFilterTags); err != nil {
+		return filter, err
+	}
+
+	switch match := query.Get("match"); match {
+	case "", "any":
+	case "all":
+		filter.MatchAllTags = true
+	default:
+		return filter, fmt.Errorf("invalid match %q, expected any or all", match)
+	}
+
+	filter.Author = strings.TrimSpace(query.Get("author"))
+	filter.Query = query.Get("q")
+
+	if filter.Since, err = parseFilterTime(query, "since", location); err != nil {
+		return filter, err
+	}
+	if filter.Until, err = parseFilterTime(query, "until", location); err != nil {
+		return filter, err
+	}
+	if filter.Since != nil && filter.Until != nil && !filter.Since.Before(*filter.Until) {
+		return filter, fmt.Errorf("since must be before until")
+	}
+
+	switch sort := database.NewsSort(query.Get("sort")); sort {
+	case "":
+		filter.Sort = database.NewsSortNewest
+	case database.NewsSortNewest, database.NewsSortOldest, database.NewsSortRelevance:
+		filter.Sort = sort
+	default:
+		return filter, fmt.Errorf("invalid sort %q, expected newest, oldest or relevance", sort)
+	}
+
+	return filter, nil
+}
+
+// parseIDList parses comma separated ids, also accepting the parameter
+// repeated, and drops duplicates.
+func parseIDList(query url.Values, key string, max int) ([]int, error) {
+	var ids []int
+	for _, value := range query[key] {
+		for part := range strings.SplitSeq(value, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			id, err := strconv.Atoi(part)
+			if err != nil || id <= 0 {
+				return nil, fmt.Errorf("invalid id %q in %v", part, key)
+			}
+			if !slices.Contains(ids, id) {
+				ids = append(ids, id)
+			}
+		}
+	}
+	if len(ids) > max {
+		return nil, fmt.Errorf("too many ids in %v, at most %d are allowed", key, max)
+	}
+	return ids, nil
+}
+
+func parseFilterTime(query url.Values, key string, location *time.Location) (*time.Time, error) {
+	value := query.Get(key)
+	if value == "" {
+		return nil, nil
+	}
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if t, err := time.ParseInLocation(layout, value, location); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid %v %q, expected an RFC 3339 timestamp or YYYY-MM-DD date", key, value)
+}
+
+// parseNewsFields reads the sparse fieldset parameters. fields lists the news
+// fields to return (tags included), include adds to and exclude removes from
+// that selection, which defaults to every field. id is always returned.
+func parseNewsFields(query url.Values) (database.NewsFields, error) {
+	known := append(slices.Clone(database.NewsFieldNames), "tags")
+
+	parse := func(key string) ([]string, error) {
+		var names []string
+		for _, value := range query[key] {
+			for name := range strings.SplitSeq(value, ",") {
+				name = strings.ToLower(strings.TrimSpace(name))
+				if name == "" {
+					continue
+				}
+				if !slices.Contains(known, name) {
+					return nil, fmt.Errorf("unknown field %q in %v, expected one of %v", name, key, strings.Join(known, ", "))
+				}
+				names = append(names, name)
+			}
+		}
+		return names, nil
+	}
+
+	fields, err := parse("fields")
+	if err != nil {
+		return database.NewsFields{}, err
+	}
+	include, err := parse("include")
+	if err != nil {
+		return database.NewsFields{}, err
+	}
+	exclude, err := parse("exclude")
+	if err != nil {
+		return database.NewsFields{}, err
+	}
+	if slices.Contains(exclude, "id") {
+		return database.NewsFields{}, fmt.Errorf("id cannot be excluded")
+	}
+
+	selected := known
+	if len(fields) > 0 {
+		selected = fields
+	}
+	selected = append(slices.Clone(selected), include...)
+	selected = slices.DeleteFunc(selected, func(name string) bool { return slices.Contains(exclude, name) })
+
+	var result database.NewsFields
+	result.OmitTags = !slices.Contains(selected, "tags")
+	for _, name := range database.NewsFieldNames {
+		if name == "id" || slices.Contains(selected, name) {
+			result.Columns = append(result.Columns, name)
+		}
+	}
+	if len(result.Columns) == len(database.NewsFieldNames) {
+		result.Columns = nil
+	}
+	return result, nil
+}
+
+func parseLimit(query url.Values, def, max int) (int, error) {
+	value := query.Get("limit")
+	if value == "" {
+		return def, nil
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit <= 0 || limit > max {
+		return 0, fmt.Errorf("invalid limit %q, expected a number between 1 and %d", value, max)
+	}
+	return limit, nil
+}
+
+// parseBool reads an optional true/false query parameter.
+func parseBool(query url.Values, key string) (bool, error) {
+	value := query.Get(key)
+	if value == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid %v %q, expected true or false", key, value)
+	}
+	return b, nil
+}
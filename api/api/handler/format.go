@@ -0,0 +1,188 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/sunba23/news/api/negotiate"
+	"github.com/sunba23/news/internal/database"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+const (
+	mediaTypeJSON    = "application/json"
+	mediaTypeNDJSON  = "application/x-ndjson"
+	mediaTypeCSV     = "text/csv"
+	mediaTypeMsgpack = "application/msgpack"
+
+	// ndjsonFlushEvery is how many NDJSON lines are written between flushes.
+	ndjsonFlushEvery = 64
+)
+
+var listMediaTypes = []string{mediaTypeJSON, mediaTypeNDJSON, mediaTypeCSV, mediaTypeMsgpack, "application/x-msgpack", "application/vnd.msgpack"}
+
+// negotiateListFormat picks the representation of a list response from the
+// Accept header, answering 406 when none of the supported ones is acceptable.
+func negotiateListFormat(w http.ResponseWriter, r *http.Request) (string, bool) {
+	w.Header().Add("Vary", "Accept")
+	format := negotiate.Best(r.Header.Get("Accept"), listMediaTypes)
+	switch format {
+	case "":
+		http.Error(w, "Not acceptable, supported types: "+strings.Join(listMediaTypes[:4], ", "), http.StatusNotAcceptable)
+		return "", false
+	case "application/x-msgpack", "application/vnd.msgpack":
+		format = mediaTypeMsgpack
+	}
+	return format, true
+}
+
+// newsView is a news item restricted to a sparse fieldset. Fields that were
+// not selected are nil and left out of the encoded response.
+type newsView struct {
+	ID        int             `json:"ID"`
+	Title     *string         `json:"Title,omitempty"`
+	Content   *string         `json:"Content,omitempty"`
+	Author    *string         `json:"Author,omitempty"`
+	Source    *string         `json:"Source,omitempty"`
+	CreatedAt *time.Time      `json:"CreatedAt,omitempty"`
+	Tags      *[]database.Tag `json:"Tags,omitempty"`
+
+	StoryID      int `json:"StoryID,omitempty"`
+	RelatedCount int `json:"RelatedCount,omitempty"`
+}
+
+// newsViews converts news loaded with a sparse fieldset for encoding.
+func newsViews(news []database.News, fields database.NewsFields) []newsView {
+	all := len(fields.Columns) == 0
+	selected := func(name string) bool { return all || slices.Contains(fields.Columns, name) }
+
+	views := make([]newsView, 0, len(news))
+	for _, n := range news {
+		view := newsView{ID: n.ID, StoryID: n.StoryID, RelatedCount: n.RelatedCount}
+		if selected("title") {
+			view.Title = &n.Title
+		}
+		if selected("content") {
+			view.Content = &n.Content
+		}
+		if selected("author") {
+			view.Author = &n.Author
+		}
+		if selected("source") {
+			view.Source = &n.Source
+		}
+		if selected("created_at") {
+			view.CreatedAt = &n.CreatedAt
+		}
+		if !fields.OmitTags {
+			view.Tags = &n.Tags
+		}
+		views = append(views, view)
+	}
+	return views
+}
+
+func writeList[T any](w http.ResponseWriter, r *http.Request, format string, items []T) {
+	w.Header().Set("Content-Type", format)
+
+	var err error
+	switch format {
+	case mediaTypeNDJSON:
+		err = writeNDJSON(w, items)
+	case mediaTypeCSV:
+		err = writeCSV(w, items)
+	case mediaTypeMsgpack:
+		// the same keys as JSON, so that every format names fields alike
+		encoder := msgpack.NewEncoder(w)
+		encoder.SetCustomStructTag("json")
+		err = encoder.Encode(items)
+	default:
+		err = json.NewEncoder(w).Encode(items)
+	}
+	if err != nil {
+		zerolog.Ctx(r.Context()).Error().Err(err).Str("format", format).Msg("encoding list response failed")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+func writeNDJSON[T any](w http.ResponseWriter, items []T) error {
+	rc := http.NewResponseController(w)
+	encoder := json.NewEncoder(w)
+	for i, item := range items {
+		if err := encoder.Encode(item); err != nil {
+			return err
+		}
+		if (i+1)%ndjsonFlushEvery == 0 {
+			rc.Flush()
+		}
+	}
+	return nil
+}
+
+func writeCSV[T any](w http.ResponseWriter, items []T) error {
+	writer := csv.NewWriter(w)
+	for i, item := range items {
+		header, record := csvRecord(item)
+		if i == 0 {
+			if err := writer.Write(header); err != nil {
+				return err
+			}
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func csvRecord(item any) ([]string, []string) {
+	switch v := item.(type) {
+	case database.News:
+		tags := make([]string, 0, len(v.Tags))
+		for _, t := range v.Tags {
+			tags = append(tags, t.Name)
+		}
+		return []string{"id", "title", "content", "author", "source", "created_at", "tags"},
+			[]string{strconv.Itoa(v.ID), v.Title, v.Content, v.Author, v.Source, v.CreatedAt.Format(time.RFC3339), strings.Join(tags, ";")}
+	case newsView:
+		var header, record []string
+		header, record = append(header, "id"), append(record, strconv.Itoa(v.ID))
+		if v.Title != nil {
+			header, record = append(header, "title"), append(record, *v.Title)
+		}
+		if v.Content != nil {
+			header, record = append(header, "content"), append(record, *v.Content)
+		}
+		if v.Author != nil {
+			header, record = append(header, "author"), append(record, *v.Author)
+		}
+		if v.Source != nil {
+			header, record = append(header, "source"), append(record, *v.Source)
+		}
+		if v.CreatedAt != nil {
+			header, record = append(header, "created_at"), append(record, v.CreatedAt.Format(time.RFC3339))
+		}
+		if v.Tags != nil {
+			tags := make([]string, 0, len(*v.Tags))
+			for _, t := range *v.Tags {
+				tags = append(tags, t.Name)
+			}
+			header, record = append(header, "tags"), append(record, strings.Join(tags, ";"))
+		}
+		return header, record
+	case database.Mute:
+		return []string{"kind", "tag_id", "value"}, []string{string(v.Kind), strconv.Itoa(v.TagID), v.Value}
+	case database.Tag:
+		return []string{"id", "name", "slug"}, []string{strconv.Itoa(v.ID), v.Name, v.Slug}
+	default:
+		b, _ := json.Marshal(v)
+		return []string{"value"}, []string{string(b)}
+	}
+}
@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNegotiateListFormat(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", mediaTypeJSON},
+		{"*/*", mediaTypeJSON},
+		{"text/csv", mediaTypeCSV},
+		{"application/json;q=0.5, application/x-ndjson", mediaTypeNDJSON},
+		// msgpack aliases are answered with the canonical type
+		{"application/x-msgpack", mediaTypeMsgpack},
+		{"application/vnd.msgpack", mediaTypeMsgpack},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/news", nil)
+		r.Header.Set("Accept", tt.accept)
+		rec := httptest.NewRecorder()
+		got, ok := negotiateListFormat(rec, r)
+		if !ok || got != tt.want {
+			t.Errorf("negotiateListFormat(%q) = %q, %v, want %q", tt.accept, got, ok, tt.want)
+		}
+		if vary := rec.Header().Get("Vary"); vary != "Accept" {
+			t.Errorf("Vary = %q, want Accept", vary)
+		}
+	}
+}
+
+func TestNegotiateListFormatNotAcceptable(t *testing.T) {
+	for _, accept := range []string{"image/png", "application/json;q=0, text/*;q=0, application/*;q=0"} {
+		r := httptest.NewRequest(http.MethodGet, "/news", nil)
+		r.Header.Set("Accept", accept)
+		rec := httptest.NewRecorder()
+		if _, ok := negotiateListFormat(rec, r); ok {
+			t.Errorf("negotiateListFormat(%q) accepted a format", accept)
+		}
+		if rec.Code != http.StatusNotAcceptable {
+			t.Errorf("status for %q = %v, want %v", accept, rec.Code, http.StatusNotAcceptable)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/sunba23/news/internal/database"
+	"github.com/sunba23/news/internal/news"
+)
+
+const readinessTimeout = 2 * time.Second
+
+// BuildTime is set at link time with
+// -ldflags "-X github.com/sunba23/news/api/handler.BuildTime=...".
+var BuildTime string
+
+type HealthHandler struct {
+	App news.App
+}
+
+type readinessResponse struct {
+	Status     string          `json:"status"`
+	Draining   bool            `json:"draining"`
+	Database   string          `json:"database"`
+	Migrations migrationStatus `json:"migrations"`
+}
+
+type migrationStatus struct {
+	Current  int  `json:"current"`
+	Expected int  `json:"expected"`
+	Pending  bool `json:"pending"`
+}
+
+type versionResponse struct {
+	Module     string `json:"module"`
+	Version    string `json:"version"`
+	GoVersion  string `json:"go_version"`
+	Revision   string `json:"revision,omitempty"`
+	RevisionAt string `json:"revision_time,omitempty"`
+	Modified   bool   `json:"modified"`
+	BuildTime  string `json:"build_time,omitempty"`
+}
+
+func (h *HealthHandler) HandleLiveness(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (h *HealthHandler) HandleReadiness(w http.ResponseWriter, r *http.Request) {
+	resp := readinessResponse{
+		Status:     "ready",
+		Draining:   h.App.Draining(),
+		Database:   "ok",
+		Migrations: migrationStatus{Expected: database.SchemaVersion},
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	repository := *h.App.Repository()
+	if err := repository.Ping(ctx); err != nil {
+		zerolog.Ctx(r.Context()).Warn().Err(err).Msg("readiness database ping failed")
+		resp.Database = "unavailable"
+	} else if version, err := repository.GetSchemaVersion(ctx); err != nil {
+		zerolog.Ctx(r.Context()).Warn().Err(err).Msg("readiness migration check failed")
+		resp.Migrations.Pending = true
+	} else {
+		resp.Migrations.Current = version
+		resp.Migrations.Pending = version < database.SchemaVersion
+	}
+
+	status := http.StatusOK
+	if resp.Draining || resp.Database != "ok" || resp.Migrations.Pending {
+		resp.Status = "not ready"
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, r, status, resp)
+}
+
+func (h *HealthHandler) HandleVersion(w http.ResponseWriter, r *http.Request) {
+	resp := versionResponse{BuildTime: BuildTime}
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		resp.Module = info.Main.Path
+		resp.Version = info.Main.Version
+		resp.GoVersion = info.GoVersion
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				resp.Revision = setting.Value
+			case "vcs.time":
+				resp.RevisionAt = setting.Value
+			case "vcs.modified":
+				resp.Modified = setting.Value == "true"
+			}
+		}
+	}
+
+	writeJSON(w, r, http.StatusOK, resp)
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		zerolog.Ctx(r.Context()).Error().Err(err).Msg("encoding response to JSON failed")
+	}
+}
@@ -4,15 +4,36 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
 
 	"github.com/gorilla/mux"
-	"github.com/rs/zerolog/log"
+	"github.com/rs/zerolog"
+	"github.com/sunba23/news/constants"
+	"github.com/sunba23/news/internal/database"
 	"github.com/sunba23/news/internal/news"
+	"github.com/sunba23/news/internal/recommend"
+)
+
+const (
+	maxBatchSize = 100
+
+	defaultRelatedLimit = 10
+	maxRelatedLimit     = 50
 )
 
 type NewsHandler struct {
-	App news.App
+	App         news.App
+	Recommender *recommend.Recommender
+}
+
+type batchGetNewsRequest struct {
+	IDs []int `json:"ids"`
+}
+
+type batchGetNewsResponse struct {
+	News    []database.News `json:"news"`
+	Missing []int           `json:"missing"`
 }
 
 func HandleRoot(w http.ResponseWriter, r *http.Request) {
@@ -20,18 +41,47 @@ func HandleRoot(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *NewsHandler) HandleGetAllNews(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Has("ids") {
+		ids, err := parseIDList(r.URL.Query(), "ids", maxBatchSize)
+		if err == nil {
+			err = checkBatchSize(ids)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		h.writeBatch(w, r, ids)
+		return
+	}
+
+	filter, err := parseNewsFilter(r.URL.Query(), preferencesFrom(r).Location())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter.ViewerID, _ = r.Context().Value(constants.UserIdContextKey).(string)
+
+	format, ok := negotiateListFormat(w, r)
+	if !ok {
+		return
+	}
+
 	repository := *h.App.Repository()
-	news, err := repository.GetAllNews(r.Context())
+	news, err := repository.SearchNews(r.Context(), filter)
 	if err != nil {
-		log.Error().Err(err).Msg(fmt.Sprintf("getting all news has failed"))
+		zerolog.Ctx(r.Context()).Error().Err(err).Msg(fmt.Sprintf("getting all news has failed"))
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(news); err != nil {
-		log.Error().Err(err).Msg("encoding all news to JSON failed")
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	if news, ok = prepareNewsList(w, r, repository, news); !ok {
+		return
 	}
+	if filter.Fields.Columns != nil || filter.Fields.OmitTags {
+		writeList(w, r, format, newsViews(news, filter.Fields))
+		return
+	}
+	writeList(w, r, format, news)
 }
 
 func (h *NewsHandler) HandleGetNewsById(w http.ResponseWriter, r *http.Request) {
@@ -47,15 +97,49 @@ func (h *NewsHandler) HandleGetNewsById(w http.ResponseWriter, r *http.Request)
 	repository := *h.App.Repository()
 	news, err := repository.GetNewsByID(r.Context(), id)
 	if err != nil {
-		log.Error().Err(err).Msg(fmt.Sprintf("getting news with id %v has failed", id))
+		zerolog.Ctx(r.Context()).Error().Err(err).Msg(fmt.Sprintf("getting news with id %v has failed", id))
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
+	if uid, ok := r.Context().Value(constants.UserIdContextKey).(string); ok && news != nil {
+		if err := repository.AddInteraction(r.Context(), uid, id, database.InteractionRead); err != nil {
+			zerolog.Ctx(r.Context()).Warn().Err(err).Msg(fmt.Sprintf("recording read of news %v for user %v failed", id, uid))
+		}
+	}
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(news); err != nil {
-		log.Error().Err(err).Msg("encoding news to JSON failed")
+		zerolog.Ctx(r.Context()).Error().Err(err).Msg("encoding news to JSON failed")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+func (h *NewsHandler) HandleGetRelatedNews(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	idStr := vars["id"]
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "invalid news id", http.StatusBadRequest)
+		return
+	}
+
+	limit, err := parseLimit(r.URL.Query(), defaultLimit(r, defaultRelatedLimit, maxRelatedLimit), maxRelatedLimit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	related, err := h.Recommender.Related(r.Context(), id, limit)
+	if err != nil {
+		zerolog.Ctx(r.Context()).Error().Err(err).Msg(fmt.Sprintf("getting news related to %v has failed", id))
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if related == nil {
+		http.Error(w, "News not found", http.StatusNotFound)
+		return
 	}
+	writeJSON(w, r, http.StatusOK, related)
 }
 
 func (h *NewsHandler) HandleGetTagsForNews(w http.ResponseWriter, r *http.Request) {
@@ -68,16 +152,80 @@ func (h *NewsHandler) HandleGetTagsForNews(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	format, ok := negotiateListFormat(w, r)
+	if !ok {
+		return
+	}
+
 	repository := *h.App.Repository()
 	tags, err := repository.GetTagsForNews(r.Context(), id)
 	if err != nil {
-		log.Error().Err(err).Msg(fmt.Sprintf("getting tags for news with  id %v has failed", id))
+		zerolog.Ctx(r.Context()).Error().Err(err).Msg(fmt.Sprintf("getting tags for news with  id %v has failed", id))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	writeList(w, r, format, tags)
+}
+
+func (h *NewsHandler) HandleBatchGetNews(w http.ResponseWriter, r *http.Request) {
+	var req batchGetNewsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var ids []int
+	for _, id := range req.IDs {
+		if id <= 0 {
+			http.Error(w, fmt.Sprintf("invalid news id %v", id), http.StatusBadRequest)
+			return
+		}
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	if err := checkBatchSize(ids); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.writeBatch(w, r, ids)
+}
+
+// checkBatchSize rejects batches of news ids that are empty or larger than
+// maxBatchSize, for both GET /news?ids= and POST /news:batchGet.
+func checkBatchSize(ids []int) error {
+	if len(ids) == 0 || len(ids) > maxBatchSize {
+		return fmt.Errorf("between 1 and %d ids are required", maxBatchSize)
+	}
+	return nil
+}
+
+// writeBatch responds with the requested news in request order, listing the
+// ids that do not exist separately.
+func (h *NewsHandler) writeBatch(w http.ResponseWriter, r *http.Request, ids []int) {
+	repository := *h.App.Repository()
+	news, err := repository.GetNewsByIDs(r.Context(), ids)
+	if err != nil {
+		zerolog.Ctx(r.Context()).Error().Err(err).Msg(fmt.Sprintf("getting news with ids %v has failed", ids))
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
+
+	resp := batchGetNewsResponse{News: news, Missing: []int{}}
+	found := make(map[int]bool, len(news))
+	for _, n := range news {
+		found[n.ID] = true
+	}
+	for _, id := range ids {
+		if !found[id] {
+			resp.Missing = append(resp.Missing, id)
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(tags); err != nil {
-		log.Error().Err(err).Msg("encoding tags to JSON failed")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		zerolog.Ctx(r.Context()).Error().Err(err).Msg("encoding news batch to JSON failed")
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 	}
 }
@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/sunba23/news/config"
+	"github.com/sunba23/news/internal/database"
+)
+
+type testApp struct {
+	repository database.Repository
+}
+
+func (a testApp) Config() *config.Config           { return &config.Config{} }
+func (a testApp) Repository() *database.Repository { return &a.repository }
+func (a testApp) Draining() bool                   { return false }
+func (a testApp) StartDraining()                   {}
+
+func TestBatchGetNews(t *testing.T) {
+	repo := database.NewMemoryRepository()
+	for _, title := range []string{"first", "second"} {
+		if err := repo.CreateNews(context.Background(), &database.News{Title: title}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h := &NewsHandler{App: testApp{repo}}
+
+	tooMany := make([]string, maxBatchSize+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprint(i + 1)
+	}
+
+	tests := []struct {
+		ids         string
+		wantStatus  int
+		wantNews    []int
+		wantMissing []int
+	}{
+		{"2,1,3", http.StatusOK, []int{2, 1}, []int{3}},
+		// duplicates count once
+		{"1,1", http.StatusOK, []int{1}, []int{}},
+		{"", http.StatusBadRequest, nil, nil},
+		{",", http.StatusBadRequest, nil, nil},
+		{"0", http.StatusBadRequest, nil, nil},
+		{"x", http.StatusBadRequest, nil, nil},
+		{strings.Join(tooMany, ","), http.StatusBadRequest, nil, nil},
+	}
+	for _, tt := range tests {
+		// both entry points accept the same batches
+		requests := map[string]func() *http.Request{
+			"GET": func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/news?ids="+tt.ids, nil)
+			},
+			"POST": func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/news:batchGet", strings.NewReader(`{"ids": [`+tt.ids+`]}`))
+			},
+		}
+		handlers := map[string]http.HandlerFunc{"GET": h.HandleGetAllNews, "POST": h.HandleBatchGetNews}
+		for method, request := range requests {
+			rec := httptest.NewRecorder()
+			handlers[method](rec, request())
+			if rec.Code != tt.wantStatus {
+				t.Errorf("%v ids %.20q: status = %v, want %v", method, tt.ids, rec.Code, tt.wantStatus)
+				continue
+			}
+			if tt.wantStatus != http.StatusOK {
+				continue
+			}
+			var resp batchGetNewsResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			var got []int
+			for _, n := range resp.News {
+				got = append(got, n.ID)
+			}
+			if !slices.Equal(got, tt.wantNews) || !slices.Equal(resp.Missing, tt.wantMissing) {
+				t.Errorf("%v ids %q = news %v, missing %v, want %v and %v", method, tt.ids, got, resp.Missing, tt.wantNews, tt.wantMissing)
+			}
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/rs/zerolog"
+	"github.com/sunba23/news/constants"
+	"github.com/sunba23/news/internal/database"
+)
+
+// maxListLimit bounds the limit parameter of news lists.
+const maxListLimit = 1000
+
+// preferencesFrom returns the preferences of the user making the request, or
+// the defaults for anonymous requests.
+func preferencesFrom(r *http.Request) database.Preferences {
+	if preferences, ok := r.Context().Value(constants.UserPreferencesContextKey).(database.Preferences); ok {
+		return preferences
+	}
+	return database.DefaultPreferences()
+}
+
+// defaultLimit is the limit of a list whose request sets none: the page size
+// the user chose, capped at max, or def.
+func defaultLimit(r *http.Request, def, max int) int {
+	if pageSize := preferencesFrom(r).PageSize; pageSize > 0 {
+		return min(pageSize, max)
+	}
+	return def
+}
+
+// prepareNewsList applies the list preferences of the user to news about to
+// be listed, each overridable with a query parameter:
+//
+//	hide_read=true   drops the news the user read
+//	collapse=true    keeps one news item per story, see collapseStories
+//	limit=20         keeps the first news only
+//
+// It answers the request itself and returns false on failure.
+func prepareNewsList(w http.ResponseWriter, r *http.Request, repository database.Repository, list []database.News) ([]database.News, bool) {
+	query := r.URL.Query()
+	preferences := preferencesFrom(r)
+
+	hideRead := preferences.HideRead
+	if query.Has("hide_read") {
+		var err error
+		if hideRead, err = parseBool(query, "hide_read"); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return nil, false
+		}
+	}
+	limit, err := parseLimit(query, preferences.PageSize, maxListLimit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+
+	uid, _ := r.Context().Value(constants.UserIdContextKey).(string)
+	if hideRead && uid != "" {
+		interactions, err := repository.GetInteractions(r.Context(), uid)
+		if err != nil {
+			zerolog.Ctx(r.Context()).Error().Err(err).Msg(fmt.Sprintf("getting interactions for user %v failed", uid))
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return nil, false
+		}
+		read := make(map[int]bool)
+		for _, interaction := range interactions {
+			if interaction.Kind == database.InteractionRead {
+				read[interaction.NewsID] = true
+			}
+		}
+		unread := make([]database.News, 0, len(list))
+		for _, n := range list {
+			if !read[n.ID] {
+				unread = append(unread, n)
+			}
+		}
+		list = unread
+	}
+
+	list, ok := collapseStories(w, r, repository, list)
+	if !ok {
+		return nil, false
+	}
+	if limit > 0 && len(list) > limit {
+		list = list[:limit]
+	}
+	return list, true
+}
@@ -0,0 +1,262 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+	"github.com/sunba23/news/constants"
+	"github.com/sunba23/news/internal/audit"
+	"github.com/sunba23/news/internal/database"
+	"github.com/sunba23/news/internal/news"
+)
+
+const (
+	maxSavedSearches     = 50
+	maxSearchNameLength  = 100
+	maxSearchQueryLength = 200
+	maxSearchWindowDays  = 365
+)
+
+type SearchesHandler struct {
+	App     news.App
+	Auditor *audit.Auditor
+}
+
+type savedSearchRequest struct {
+	Name       string `json:"name"`
+	Query      string `json:"query"`
+	Tags       []int  `json:"tags"`
+	Match      string `json:"match"`
+	WindowDays int    `json:"window_days"`
+}
+
+type searchMatchView struct {
+	MatchedAt time.Time     `json:"matched_at"`
+	News      database.News `json:"news"`
+}
+
+func (h *SearchesHandler) HandleGetSearches(w http.ResponseWriter, r *http.Request) {
+	uid := r.Context().Value(constants.UserIdContextKey).(string)
+
+	repository := *h.App.Repository()
+	searches, err := repository.GetSavedSearches(r.Context(), uid)
+	if err != nil {
+		zerolog.Ctx(r.Context()).Error().Err(err).Msg(fmt.Sprintf("getting saved searches for user %v failed", uid))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if searches == nil {
+		searches = []database.SavedSearch{}
+	}
+	writeJSON(w, r, http.StatusOK, searches)
+}
+
+func (h *SearchesHandler) HandleCreateSearch(w http.ResponseWriter, r *http.Request) {
+	uid := r.Context().Value(constants.UserIdContextKey).(string)
+
+	var req savedSearchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	search, err := req.toSavedSearch(uid)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	repository := *h.App.Repository()
+	existing, err := repository.GetSavedSearches(r.Context(), uid)
+	if err != nil {
+		zerolog.Ctx(r.Context()).Error().Err(err).Msg(fmt.Sprintf("getting saved searches for user %v failed", uid))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if len(existing) >= maxSavedSearches {
+		http.Error(w, fmt.Sprintf("at most %d saved searches are allowed", maxSavedSearches), http.StatusConflict)
+		return
+	}
+	tags, err := repository.GetAllTags(r.Context())
+	if err != nil {
+		zerolog.Ctx(r.Context()).Error().Err(err).Msg("getting all tags has failed")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	for _, tagID := range search.TagIDs {
+		if !slices.ContainsFunc(tags, func(t database.Tag) bool { return t.ID == tagID }) {
+			http.Error(w, fmt.Sprintf("unknown tag %d", tagID), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := repository.CreateSavedSearch(r.Context(), &search); err != nil {
+		zerolog.Ctx(r.Context()).Error().Err(err).Msg(fmt.Sprintf("saving search for user %v failed", uid))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	h.Auditor.Record(r, audit.Event(audit.ActionSearchCreate, audit.TargetSearch, strconv.Itoa(search.ID)))
+	writeJSON(w, r, http.StatusCreated, search)
+}
+
+func (h *SearchesHandler) HandleGetSearch(w http.ResponseWriter, r *http.Request) {
+	search, ok := h.searchFromPath(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, r, http.StatusOK, search)
+}
+
+func (h *SearchesHandler) HandleDeleteSearch(w http.ResponseWriter, r *http.Request) {
+	uid := r.Context().Value(constants.UserIdContextKey).(string)
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid search id", http.StatusBadRequest)
+		return
+	}
+
+	repository := *h.App.Repository()
+	if err := repository.DeleteSavedSearch(r.Context(), uid, id); err != nil {
+		zerolog.Ctx(r.Context()).Error().Err(err).Msg(fmt.Sprintf("deleting saved search %v for user %v failed", id, uid))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	h.Auditor.Record(r, audit.Event(audit.ActionSearchDelete, audit.TargetSearch, strconv.Itoa(id)))
+}
+
+// HandleGetSearchResults runs a saved search over all news.
+func (h *SearchesHandler) HandleGetSearchResults(w http.ResponseWriter, r *http.Request) {
+	search, ok := h.searchFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	format, ok := negotiateListFormat(w, r)
+	if !ok {
+		return
+	}
+
+	repository := *h.App.Repository()
+	results, err := repository.SearchNews(r.Context(), search.Filter(time.Now().UTC()))
+	if err != nil {
+		zerolog.Ctx(r.Context()).Error().Err(err).Msg(fmt.Sprintf("running saved search %v has failed", search.ID))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if results, ok = prepareNewsList(w, r, repository, results); !ok {
+		return
+	}
+	writeList(w, r, format, results)
+}
+
+// HandleGetSearchMatches lists the news ingested since the search was saved
+// that matched it, most recent match first.
+func (h *SearchesHandler) HandleGetSearchMatches(w http.ResponseWriter, r *http.Request) {
+	search, ok := h.searchFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	repository := *h.App.Repository()
+	matches, err := repository.GetSearchMatches(r.Context(), search.ID)
+	if err != nil {
+		zerolog.Ctx(r.Context()).Error().Err(err).Msg(fmt.Sprintf("getting matches of saved search %v has failed", search.ID))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	ids := make([]int, 0, len(matches))
+	for _, match := range matches {
+		ids = append(ids, match.NewsID)
+	}
+	matched, err := repository.GetNewsByIDs(r.Context(), ids)
+	if err != nil {
+		zerolog.Ctx(r.Context()).Error().Err(err).Msg(fmt.Sprintf("getting news matching saved search %v has failed", search.ID))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	byID := make(map[int]database.News, len(matched))
+	for _, n := range matched {
+		byID[n.ID] = n
+	}
+	views := make([]searchMatchView, 0, len(matches))
+	for _, match := range matches {
+		if n, ok := byID[match.NewsID]; ok {
+			views = append(views, searchMatchView{MatchedAt: match.MatchedAt, News: n})
+		}
+	}
+	writeJSON(w, r, http.StatusOK, views)
+}
+
+// searchFromPath loads the current user's saved search named by the {id}
+// route variable. It answers the request itself and returns false on failure.
+func (h *SearchesHandler) searchFromPath(w http.ResponseWriter, r *http.Request) (*database.SavedSearch, bool) {
+	uid := r.Context().Value(constants.UserIdContextKey).(string)
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid search id", http.StatusBadRequest)
+		return nil, false
+	}
+
+	repository := *h.App.Repository()
+	search, err := repository.GetSavedSearch(r.Context(), uid, id)
+	if err != nil {
+		zerolog.Ctx(r.Context()).Error().Err(err).Msg(fmt.Sprintf("getting saved search %v for user %v failed", id, uid))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return nil, false
+	}
+	if search == nil {
+		http.Error(w, "Search not found", http.StatusNotFound)
+		return nil, false
+	}
+	return search, true
+}
+
+func (req savedSearchRequest) toSavedSearch(userID string) (database.SavedSearch, error) {
+	search := database.SavedSearch{
+		UserID:     userID,
+		Name:       strings.TrimSpace(req.Name),
+		Query:      strings.Join(database.QueryWords(req.Query), " "),
+		TagIDs:     []int{},
+		WindowDays: req.WindowDays,
+	}
+	if search.Name == "" || len(search.Name) > maxSearchNameLength {
+		return search, fmt.Errorf("name must be 1 to %d characters long", maxSearchNameLength)
+	}
+	if len(search.Query) > maxSearchQueryLength {
+		return search, fmt.Errorf("query must be at most %d characters long", maxSearchQueryLength)
+	}
+	for _, tagID := range req.Tags {
+		if tagID <= 0 {
+			return search, fmt.Errorf("invalid tag id %d", tagID)
+		}
+		if !slices.Contains(search.TagIDs, tagID) {
+			search.TagIDs = append(search.TagIDs, tagID)
+		}
+	}
+	if len(search.TagIDs) > maxFilterTags {
+		return search, fmt.Errorf("too many tags, at most %d are allowed", maxFilterTags)
+	}
+	if search.Query == "" && len(search.TagIDs) == 0 {
+		return search, fmt.Errorf("a search needs a query or tags")
+	}
+	switch req.Match {
+	case "", "any":
+	case "all":
+		search.MatchAllTags = true
+	default:
+		return search, fmt.Errorf("invalid match %q, expected any or all", req.Match)
+	}
+	if search.WindowDays < 0 || search.WindowDays > maxSearchWindowDays {
+		return search, fmt.Errorf("window_days must be between 0 and %d", maxSearchWindowDays)
+	}
+	return search, nil
+}
@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+	"github.com/sunba23/news/internal/database"
+	"github.com/sunba23/news/internal/news"
+	"github.com/sunba23/news/internal/stories"
+)
+
+type StoriesHandler struct {
+	App news.App
+}
+
+func (h *StoriesHandler) HandleGetStory(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	idStr := vars["id"]
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "invalid story id", http.StatusBadRequest)
+		return
+	}
+
+	repository := *h.App.Repository()
+	story, err := repository.GetStoryCluster(r.Context(), id)
+	if err != nil {
+		zerolog.Ctx(r.Context()).Error().Err(err).Msg(fmt.Sprintf("getting story with id %v has failed", id))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if story == nil {
+		http.Error(w, "Story not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, story)
+}
+
+// collapseStories keeps one news item per story when the request asks for it
+// with collapse=true, or by default when the user hides duplicates. It
+// answers the request itself and returns false on failure.
+func collapseStories(w http.ResponseWriter, r *http.Request, repository database.Repository, list []database.News) ([]database.News, bool) {
+	collapse := preferencesFrom(r).HideDuplicates
+	if r.URL.Query().Has("collapse") {
+		var err error
+		if collapse, err = parseBool(r.URL.Query(), "collapse"); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return nil, false
+		}
+	}
+	if !collapse {
+		return list, true
+	}
+
+	collapsed, err := stories.Collapse(r.Context(), repository, list)
+	if err != nil {
+		zerolog.Ctx(r.Context()).Error().Err(err).Msg("collapsing news into stories has failed")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return nil, false
+	}
+	return collapsed, true
+}
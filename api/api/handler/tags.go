@@ -2,53 +2,224 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
-	"github.com/rs/zerolog/log"
+	"github.com/rs/zerolog"
+	"github.com/sunba23/news/constants"
+	"github.com/sunba23/news/internal/audit"
+	"github.com/sunba23/news/internal/database"
 	"github.com/sunba23/news/internal/news"
 )
 
 type TagsHandler struct {
-	App news.App
+	App     news.App
+	Auditor *audit.Auditor
 }
 
 func (h *TagsHandler) HandleGetAllTags(w http.ResponseWriter, r *http.Request) {
+	format, ok := negotiateListFormat(w, r)
+	if !ok {
+		return
+	}
+
+	repository := *h.App.Repository()
+	if name := r.URL.Query().Get("name"); name != "" {
+		tag, err := repository.ResolveTag(r.Context(), name)
+		if err != nil {
+			zerolog.Ctx(r.Context()).Error().Err(err).Msg(fmt.Sprintf("resolving tag %q has failed", name))
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		tags := []database.Tag{}
+		if tag != nil {
+			tags = append(tags, *tag)
+		}
+		writeList(w, r, format, tags)
+		return
+	}
+
+	tags, err := repository.GetAllTags(r.Context())
+	if err != nil {
+		zerolog.Ctx(r.Context()).Error().Err(err).Msg("getting all tags has failed")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	writeList(w, r, format, tags)
+}
+
+func (h *TagsHandler) HandleGetTagTree(w http.ResponseWriter, r *http.Request) {
 	repository := *h.App.Repository()
 	tags, err := repository.GetAllTags(r.Context())
 	if err != nil {
-		log.Error().Err(err).Msg("getting all tags has failed")
+		zerolog.Ctx(r.Context()).Error().Err(err).Msg("getting all tags has failed")
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(tags); err != nil {
-		log.Error().Err(err).Msg("encoding tags to JSON failed")
+	aliases, err := repository.GetTagAliases(r.Context())
+	if err != nil {
+		zerolog.Ctx(r.Context()).Error().Err(err).Msg("getting tag aliases has failed")
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
 	}
+	writeJSON(w, r, http.StatusOK, database.NewTagTree(tags, aliases))
 }
 
-func (h *TagsHandler) HandleGetNewsByTag(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	idStr := vars["id"]
+func (h *TagsHandler) HandleGetTag(w http.ResponseWriter, r *http.Request) {
+	repository := *h.App.Repository()
+	id, ok := tagIDFromPath(w, r, repository)
+	if !ok {
+		return
+	}
 
-	id, err := strconv.Atoi(idStr)
+	tag, err := repository.GetTagDetail(r.Context(), id)
 	if err != nil {
-		http.Error(w, "invalid news id", http.StatusBadRequest)
+		zerolog.Ctx(r.Context()).Error().Err(err).Msg(fmt.Sprintf("getting tag with id %v has failed", id))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if tag == nil {
+		http.Error(w, "Tag not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, tag)
+}
+
+func (h *TagsHandler) HandleGetNewsByTag(w http.ResponseWriter, r *http.Request) {
+	descendants, err := parseBool(r.URL.Query(), "descendants")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	format, ok := negotiateListFormat(w, r)
+	if !ok {
 		return
 	}
+
 	repository := *h.App.Repository()
-	news, err := repository.GetNewsByTag(r.Context(), id)
+	id, ok := tagIDFromPath(w, r, repository)
+	if !ok {
+		return
+	}
+	uid, _ := r.Context().Value(constants.UserIdContextKey).(string)
+	news, err := repository.GetNewsByTag(r.Context(), id, descendants, uid)
 	if err != nil {
-		log.Error().Err(err).Msg(fmt.Sprintf("getting news by tag id %v has failed", id))
+		zerolog.Ctx(r.Context()).Error().Err(err).Msg(fmt.Sprintf("getting news by tag id %v has failed", id))
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(news); err != nil {
-		log.Error().Err(err).Msg("encoding news to JSON failed")
+	if news, ok = prepareNewsList(w, r, repository, news); !ok {
+		return
+	}
+	writeList(w, r, format, news)
+}
+
+type setTagParentRequest struct {
+	ParentID *int `json:"parent_id"`
+}
+
+type addTagAliasRequest struct {
+	Alias string `json:"alias"`
+}
+
+func (h *TagsHandler) HandleSetTagParent(w http.ResponseWriter, r *http.Request) {
+	var req setTagParentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	repository := *h.App.Repository()
+	id, ok := tagIDFromPath(w, r, repository)
+	if !ok {
+		return
+	}
+	err := repository.SetTagParent(r.Context(), id, req.ParentID)
+	if !h.writeTaxonomyError(w, r, err) {
+		return
+	}
+	h.recordAdminEvent(r, audit.Event(audit.ActionTagParentSet, audit.TargetTag, strconv.Itoa(id)))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *TagsHandler) HandleAddTagAlias(w http.ResponseWriter, r *http.Request) {
+	var req addTagAliasRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Alias) == "" {
+		http.Error(w, "invalid request body, expected an alias", http.StatusBadRequest)
+		return
+	}
+
+	repository := *h.App.Repository()
+	id, ok := tagIDFromPath(w, r, repository)
+	if !ok {
+		return
+	}
+	err := repository.AddTagAlias(r.Context(), id, req.Alias)
+	if !h.writeTaxonomyError(w, r, err) {
+		return
+	}
+	h.recordAdminEvent(r, audit.Event(audit.ActionTagAliasAdd, audit.TargetTagAlias, req.Alias))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *TagsHandler) HandleDeleteTagAlias(w http.ResponseWriter, r *http.Request) {
+	alias := mux.Vars(r)["alias"]
+
+	repository := *h.App.Repository()
+	err := repository.RemoveTagAlias(r.Context(), alias)
+	if !h.writeTaxonomyError(w, r, err) {
+		return
+	}
+	h.recordAdminEvent(r, audit.Event(audit.ActionTagAliasRemove, audit.TargetTagAlias, alias))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// recordAdminEvent records a change made through the admin listener.
+func (h *TagsHandler) recordAdminEvent(r *http.Request, event database.AuditEvent) {
+	event.ActorID = adminActor(r)
+	h.Auditor.Record(r, event)
+}
+
+// writeTaxonomyError answers a failed taxonomy change, returning whether
+// there was no error.
+func (h *TagsHandler) writeTaxonomyError(w http.ResponseWriter, r *http.Request, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, database.ErrTagNotFound):
+		http.Error(w, "Tag not found", http.StatusNotFound)
+	case errors.Is(err, database.ErrTagCycle), errors.Is(err, database.ErrAliasTaken):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		zerolog.Ctx(r.Context()).Error().Err(err).Msg("changing the tag taxonomy has failed")
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 	}
+	return false
+}
+
+// tagIDFromPath reads the {tag} route variable, either a tag id or a slug,
+// name or alias resolved with ResolveTag. It answers the request itself and
+// returns false on failure.
+func tagIDFromPath(w http.ResponseWriter, r *http.Request, repository database.Repository) (int, bool) {
+	value := mux.Vars(r)["tag"]
+	if id, err := strconv.Atoi(value); err == nil {
+		return id, true
+	}
+
+	tag, err := repository.ResolveTag(r.Context(), value)
+	if err != nil {
+		zerolog.Ctx(r.Context()).Error().Err(err).Msg(fmt.Sprintf("resolving tag %q has failed", value))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return 0, false
+	}
+	if tag == nil {
+		http.Error(w, "Tag not found", http.StatusNotFound)
+		return 0, false
+	}
+	return tag.ID, true
 }
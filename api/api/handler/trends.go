@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/sunba23/news/internal/news"
+	"github.com/sunba23/news/internal/trends"
+)
+
+const (
+	defaultTrendsLimit = 20
+	maxTrendsLimit     = trends.MaxNews
+)
+
+type TrendsHandler struct {
+	App     news.App
+	Tracker *trends.Tracker
+}
+
+type tagTrendsResponse struct {
+	Window     trends.Window     `json:"window"`
+	ComputedAt time.Time         `json:"computed_at"`
+	Tags       []trends.TagTrend `json:"tags"`
+}
+
+type newsTrendsResponse struct {
+	Window     trends.Window      `json:"window"`
+	ComputedAt time.Time          `json:"computed_at"`
+	News       []trends.NewsTrend `json:"news"`
+}
+
+func (h *TrendsHandler) HandleGetTagTrends(w http.ResponseWriter, r *http.Request) {
+	window, limit, snapshot, ok := h.parseRequest(w, r)
+	if !ok {
+		return
+	}
+
+	tags := snapshot.Tags[window]
+	if len(tags) > limit {
+		tags = tags[:limit]
+	}
+	writeJSON(w, r, http.StatusOK, tagTrendsResponse{Window: window, ComputedAt: snapshot.ComputedAt, Tags: tags})
+}
+
+func (h *TrendsHandler) HandleGetNewsTrends(w http.ResponseWriter, r *http.Request) {
+	window, limit, snapshot, ok := h.parseRequest(w, r)
+	if !ok {
+		return
+	}
+
+	news := snapshot.News[window]
+	if len(news) > limit {
+		news = news[:limit]
+	}
+	writeJSON(w, r, http.StatusOK, newsTrendsResponse{Window: window, ComputedAt: snapshot.ComputedAt, News: news})
+}
+
+func (h *TrendsHandler) parseRequest(w http.ResponseWriter, r *http.Request) (trends.Window, int, *trends.Snapshot, bool) {
+	window, err := trends.ParseWindow(r.URL.Query().Get("window"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return "", 0, nil, false
+	}
+	limit, err := parseLimit(r.URL.Query(), defaultLimit(r, defaultTrendsLimit, maxTrendsLimit), maxTrendsLimit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return "", 0, nil, false
+	}
+
+	snapshot := h.Tracker.Snapshot()
+	if snapshot == nil {
+		w.Header().Set("Retry-After", "5")
+		http.Error(w, "Trends are not computed yet", http.StatusServiceUnavailable)
+		return "", 0, nil, false
+	}
+	return window, limit, snapshot, true
+}
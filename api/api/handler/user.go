@@ -5,89 +5,349 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
-	"github.com/rs/zerolog/log"
+	"github.com/gorilla/sessions"
+	"github.com/rs/zerolog"
 	"github.com/sunba23/news/constants"
+	"github.com/sunba23/news/internal/audit"
+	"github.com/sunba23/news/internal/database"
 	"github.com/sunba23/news/internal/news"
+	"github.com/sunba23/news/internal/recommend"
+)
+
+const (
+	defaultRecommendationLimit = 20
+	maxRecommendationLimit     = 100
 )
 
 type UserHandler struct {
-	App news.App
+	App          news.App
+	Recommender  *recommend.Recommender
+	SessionStore *sessions.CookieStore
+	Auditor      *audit.Auditor
+}
+
+type profileView struct {
+	ID          string               `json:"id"`
+	Email       string               `json:"email"`
+	CreatedAt   time.Time            `json:"created_at"`
+	Preferences database.Preferences `json:"preferences"`
+}
+
+func (h *UserHandler) HandleGetProfile(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, r, http.StatusOK, profileView{
+		ID:          user.ID,
+		Email:       user.Email,
+		CreatedAt:   user.CreatedAt,
+		Preferences: user.Preferences,
+	})
+}
+
+// HandleUpdatePreferences changes the preferences set in the body, leaving
+// the others as they are.
+func (h *UserHandler) HandleUpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	preferences := user.Preferences
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&preferences); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if err := preferences.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	repository := *h.App.Repository()
+	if err := repository.UpdatePreferences(r.Context(), user.ID, preferences); err != nil {
+		zerolog.Ctx(r.Context()).Error().Err(err).Msg(fmt.Sprintf("updating preferences for user %v failed", user.ID))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	h.Auditor.Record(r, audit.Event(audit.ActionPreferencesUpdate, audit.TargetUser, user.ID))
+	writeJSON(w, r, http.StatusOK, preferences)
+}
+
+// currentUser loads the user making the request. It answers the request
+// itself and returns false on failure.
+func (h *UserHandler) currentUser(w http.ResponseWriter, r *http.Request) (*database.User, bool) {
+	uid := r.Context().Value(constants.UserIdContextKey).(string)
+
+	repository := *h.App.Repository()
+	user, err := repository.GetUserByID(r.Context(), uid)
+	if err != nil {
+		zerolog.Ctx(r.Context()).Error().Err(err).Msg(fmt.Sprintf("getting user %v failed", uid))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return nil, false
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return nil, false
+	}
+	return user, true
 }
 
 func (h *UserHandler) HandleAddFavoriteTag(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	idStr := vars["id"]
+	uid := r.Context().Value(constants.UserIdContextKey).(string)
 
-	id, err := strconv.Atoi(idStr)
+	repository := *h.App.Repository()
+	id, ok := tagIDFromPath(w, r, repository)
+	if !ok {
+		return
+	}
+	err := repository.AddFavoriteTag(r.Context(), uid, id)
 	if err != nil {
-		http.Error(w, "invalid news id", http.StatusBadRequest)
+		zerolog.Ctx(r.Context()).Error().Err(err).Msg(fmt.Sprintf("adding tag for user %v failed", uid))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
+	h.Auditor.Record(r, audit.Event(audit.ActionFavoriteTagAdd, audit.TargetTag, strconv.Itoa(id)))
+}
 
+func (h *UserHandler) HandleDeleteFavoriteTag(w http.ResponseWriter, r *http.Request) {
 	uid := r.Context().Value(constants.UserIdContextKey).(string)
 
 	repository := *h.App.Repository()
-	err = repository.AddFavoriteTag(r.Context(), uid, id)
+	id, ok := tagIDFromPath(w, r, repository)
+	if !ok {
+		return
+	}
+	err := repository.RemoveFavoriteTag(r.Context(), uid, id)
 	if err != nil {
-		log.Error().Err(err).Msg(fmt.Sprintf("adding tag for user %v failed", uid))
+		zerolog.Ctx(r.Context()).Error().Err(err).Msg(fmt.Sprintf("deleting tag for user %v failed", uid))
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
+	h.Auditor.Record(r, audit.Event(audit.ActionFavoriteTagRemove, audit.TargetTag, strconv.Itoa(id)))
 }
 
-func (h *UserHandler) HandleDeleteFavoriteTag(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	idStr := vars["id"]
+func (h *UserHandler) HandleGetFavoriteTags(w http.ResponseWriter, r *http.Request) {
+	uid := r.Context().Value(constants.UserIdContextKey).(string)
 
-	id, err := strconv.Atoi(idStr)
+	format, ok := negotiateListFormat(w, r)
+	if !ok {
+		return
+	}
+
+	repository := *h.App.Repository()
+	tags, err := repository.GetFavoriteTags(r.Context(), uid)
 	if err != nil {
-		http.Error(w, "invalid news id", http.StatusBadRequest)
+		zerolog.Ctx(r.Context()).Error().Err(err).Msg(fmt.Sprintf("getting favorite tags for user %v failed", uid))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
+	writeList(w, r, format, tags)
+}
 
+func (h *UserHandler) HandleGetFavoriteNews(w http.ResponseWriter, r *http.Request) {
 	uid := r.Context().Value(constants.UserIdContextKey).(string)
 
+	descendants, err := parseBool(r.URL.Query(), "descendants")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	format, ok := negotiateListFormat(w, r)
+	if !ok {
+		return
+	}
+
 	repository := *h.App.Repository()
-	err = repository.RemoveFavoriteTag(r.Context(), uid, id)
+	news, err := repository.GetFavoriteNews(r.Context(), uid, descendants)
 	if err != nil {
-		log.Error().Err(err).Msg(fmt.Sprintf("deleting tag for user %v failed", uid))
+		zerolog.Ctx(r.Context()).Error().Err(err).Msg(fmt.Sprintf("getting favorite news for user %v failed", uid))
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
+	if news, ok = prepareNewsList(w, r, repository, news); !ok {
+		return
+	}
+	writeList(w, r, format, news)
 }
 
-func (h *UserHandler) HandleGetFavoriteTags(w http.ResponseWriter, r *http.Request) {
+func (h *UserHandler) HandleGetMutes(w http.ResponseWriter, r *http.Request) {
 	uid := r.Context().Value(constants.UserIdContextKey).(string)
 
+	format, ok := negotiateListFormat(w, r)
+	if !ok {
+		return
+	}
+
 	repository := *h.App.Repository()
-	tags, err := repository.GetFavoriteTags(r.Context(), uid)
+	mutes, err := repository.GetMutes(r.Context(), uid)
 	if err != nil {
-		log.Error().Err(err).Msg(fmt.Sprintf("getting favorite tags for user %v failed", uid))
+		zerolog.Ctx(r.Context()).Error().Err(err).Msg(fmt.Sprintf("getting mutes for user %v failed", uid))
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(tags); err != nil {
-		log.Error().Err(err).Msg("encoding tags to JSON failed")
+	writeList(w, r, format, mutes)
+}
+
+func (h *UserHandler) HandleAddMute(w http.ResponseWriter, r *http.Request) {
+	h.changeMute(w, r, true)
+}
+
+func (h *UserHandler) HandleDeleteMute(w http.ResponseWriter, r *http.Request) {
+	h.changeMute(w, r, false)
+}
+
+// changeMute adds or removes the mute described by the route: a tag, an
+// author, a source or a title keyword.
+func (h *UserHandler) changeMute(w http.ResponseWriter, r *http.Request, add bool) {
+	uid := r.Context().Value(constants.UserIdContextKey).(string)
+	vars := mux.Vars(r)
+
+	repository := *h.App.Repository()
+	var mute database.Mute
+	switch vars["kind"] {
+	case "tags":
+		id, ok := tagIDFromPath(w, r, repository)
+		if !ok {
+			return
+		}
+		mute = database.Mute{Kind: database.MuteTag, TagID: id}
+	case "authors":
+		mute = database.Mute{Kind: database.MuteAuthor, Value: vars["value"]}
+	case "sources":
+		mute = database.Mute{Kind: database.MuteSource, Value: vars["value"]}
+	case "keywords":
+		mute = database.Mute{Kind: database.MuteKeyword, Value: vars["value"]}
+	}
+	if mute.Kind != database.MuteTag && strings.TrimSpace(mute.Value) == "" {
+		http.Error(w, "empty mute", http.StatusBadRequest)
+		return
+	}
+
+	var err error
+	if add {
+		err = repository.AddMute(r.Context(), uid, mute)
+	} else {
+		err = repository.RemoveMute(r.Context(), uid, mute)
+	}
+	if err != nil {
+		zerolog.Ctx(r.Context()).Error().Err(err).Msg(fmt.Sprintf("changing %v mute for user %v failed", mute.Kind, uid))
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	action, target := audit.ActionMuteRemove, mute.Value
+	if add {
+		action = audit.ActionMuteAdd
 	}
+	if mute.Kind == database.MuteTag {
+		target = strconv.Itoa(mute.TagID)
+	}
+	h.Auditor.Record(r, audit.Event(action, string(mute.Kind), target))
 }
 
-func (h *UserHandler) HandleGetFavoriteNews(w http.ResponseWriter, r *http.Request) {
+func (h *UserHandler) HandleGetRecommendations(w http.ResponseWriter, r *http.Request) {
+	uid := r.Context().Value(constants.UserIdContextKey).(string)
+
+	limit, err := parseLimit(r.URL.Query(), defaultLimit(r, defaultRecommendationLimit, maxRecommendationLimit), maxRecommendationLimit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	recommendations, err := h.Recommender.Recommend(r.Context(), uid, limit)
+	if err != nil {
+		zerolog.Ctx(r.Context()).Error().Err(err).Msg(fmt.Sprintf("getting recommendations for user %v failed", uid))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if recommendations == nil {
+		recommendations = []recommend.Recommendation{}
+	}
+	writeJSON(w, r, http.StatusOK, recommendations)
+}
+
+func (h *UserHandler) HandleAddBookmark(w http.ResponseWriter, r *http.Request) {
+	h.changeBookmark(w, r, true)
+}
+
+func (h *UserHandler) HandleDeleteBookmark(w http.ResponseWriter, r *http.Request) {
+	h.changeBookmark(w, r, false)
+}
+
+func (h *UserHandler) changeBookmark(w http.ResponseWriter, r *http.Request, add bool) {
+	vars := mux.Vars(r)
+	idStr := vars["id"]
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "invalid news id", http.StatusBadRequest)
+		return
+	}
+
+	uid := r.Context().Value(constants.UserIdContextKey).(string)
+
+	repository := *h.App.Repository()
+	if add {
+		news, err := repository.GetNewsByID(r.Context(), id)
+		if err != nil {
+			zerolog.Ctx(r.Context()).Error().Err(err).Msg(fmt.Sprintf("getting news with id %v has failed", id))
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if news == nil {
+			http.Error(w, "News not found", http.StatusNotFound)
+			return
+		}
+		err = repository.AddInteraction(r.Context(), uid, id, database.InteractionBookmark)
+	} else {
+		err = repository.RemoveInteraction(r.Context(), uid, id, database.InteractionBookmark)
+	}
+	if err != nil {
+		zerolog.Ctx(r.Context()).Error().Err(err).Msg(fmt.Sprintf("changing bookmark of news %v for user %v failed", id, uid))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	action := audit.ActionBookmarkRemove
+	if add {
+		action = audit.ActionBookmarkAdd
+	}
+	h.Auditor.Record(r, audit.Event(action, audit.TargetNews, strconv.Itoa(id)))
+}
+
+func (h *UserHandler) HandleGetBookmarks(w http.ResponseWriter, r *http.Request) {
 	uid := r.Context().Value(constants.UserIdContextKey).(string)
 
+	format, ok := negotiateListFormat(w, r)
+	if !ok {
+		return
+	}
+
 	repository := *h.App.Repository()
-	tags, err := repository.GetFavoriteNews(r.Context(), uid)
+	interactions, err := repository.GetInteractions(r.Context(), uid)
 	if err != nil {
-		log.Error().Err(err).Msg(fmt.Sprintf("getting favorite news for user %v failed", uid))
+		zerolog.Ctx(r.Context()).Error().Err(err).Msg(fmt.Sprintf("getting bookmarks for user %v failed", uid))
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(tags); err != nil {
-		log.Error().Err(err).Msg("encoding news to JSON failed")
+
+	var ids []int
+	for _, interaction := range interactions {
+		if interaction.Kind == database.InteractionBookmark {
+			ids = append(ids, interaction.NewsID)
+		}
+	}
+	bookmarks, err := repository.GetNewsByIDs(r.Context(), ids)
+	if err != nil {
+		zerolog.Ctx(r.Context()).Error().Err(err).Msg(fmt.Sprintf("getting bookmarked news for user %v failed", uid))
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
 	}
+	writeList(w, r, format, bookmarks)
 }
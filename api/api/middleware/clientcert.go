@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/rs/zerolog"
+)
+
+// ClientCertificateMiddleware only lets through requests authenticated with a
+// client certificate verified against ADMIN_TLS_CLIENT_CA_FILE. It guards the
+// whole admin listener, in case it is ever served without that CA.
+func ClientCertificateMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+			zerolog.Ctx(r.Context()).Warn().Msg("refused an admin request without a verified client certificate")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,224 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gorilla/mux"
+	"github.com/klauspost/compress/zstd"
+	"github.com/rs/zerolog"
+	"github.com/sunba23/news/api/negotiate"
+	"github.com/sunba23/news/internal/news"
+)
+
+var encodings = []string{"br", "zstd", "gzip"}
+
+// encoder is a compressor that can be reused for another response.
+type encoder interface {
+	io.WriteCloser
+	Reset(w io.Writer)
+}
+
+// Encoders are pooled, as setting one up allocates large buffers, zstd's in
+// particular.
+var (
+	brotliEncoders = sync.Pool{New: func() any {
+		return brotli.NewWriterLevel(nil, brotli.DefaultCompression)
+	}}
+	zstdEncoders = sync.Pool{New: func() any {
+		// a single goroutine per encoder, responses being compressed
+		// concurrently already
+		encoder, _ := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault), zstd.WithEncoderConcurrency(1))
+		return encoder
+	}}
+	gzipEncoders = sync.Pool{New: func() any {
+		encoder, _ := gzip.NewWriterLevel(nil, gzip.DefaultCompression)
+		return encoder
+	}}
+)
+
+func encoderPool(encoding string) *sync.Pool {
+	switch encoding {
+	case "br":
+		return &brotliEncoders
+	case "zstd":
+		return &zstdEncoders
+	default:
+		return &gzipEncoders
+	}
+}
+
+// newEncoder takes an encoder from the pool, writing to w.
+func newEncoder(encoding string, w io.Writer) encoder {
+	encoder := encoderPool(encoding).Get().(encoder)
+	encoder.Reset(w)
+	return encoder
+}
+
+// releaseEncoder returns a closed encoder to the pool.
+func releaseEncoder(encoding string, encoder encoder) {
+	// not to keep the response writer alive
+	encoder.Reset(io.Discard)
+	encoderPool(encoding).Put(encoder)
+}
+
+// compressResponseWriter buffers the first minSize bytes of a response. If the
+// response stays smaller it is sent as is, otherwise the buffered and all
+// following bytes go through the negotiated encoder. Flushing commits to
+// compression early so streamed responses reach the client incrementally.
+type compressResponseWriter struct {
+	http.ResponseWriter
+	encoding string
+	minSize  int
+	logger   *zerolog.Logger
+
+	statusCode  int
+	buf         bytes.Buffer
+	encoder     encoder
+	passthrough bool
+	wroteHeader bool
+}
+
+func (cw *compressResponseWriter) WriteHeader(code int) {
+	if cw.statusCode == 0 {
+		cw.statusCode = code
+	}
+}
+
+func (cw *compressResponseWriter) Write(b []byte) (int, error) {
+	if cw.statusCode == 0 {
+		cw.statusCode = http.StatusOK
+	}
+	if cw.passthrough {
+		return cw.ResponseWriter.Write(b)
+	}
+	if cw.encoder != nil {
+		return cw.encoder.Write(b)
+	}
+
+	if !cw.compressible() {
+		cw.startPassthrough()
+		return cw.ResponseWriter.Write(b)
+	}
+
+	cw.buf.Write(b)
+	if cw.buf.Len() >= cw.minSize {
+		if err := cw.startEncoding(); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+func (cw *compressResponseWriter) compressible() bool {
+	header := cw.Header()
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+	if cw.statusCode < http.StatusOK || cw.statusCode == http.StatusNoContent || cw.statusCode == http.StatusNotModified {
+		return false
+	}
+	return !strings.HasPrefix(header.Get("Content-Type"), "image/")
+}
+
+func (cw *compressResponseWriter) startPassthrough() {
+	cw.passthrough = true
+	cw.writeHeader()
+	if cw.buf.Len() > 0 {
+		cw.ResponseWriter.Write(cw.buf.Bytes())
+		cw.buf.Reset()
+	}
+}
+
+func (cw *compressResponseWriter) startEncoding() error {
+	header := cw.Header()
+	header.Set("Content-Encoding", cw.encoding)
+	header.Del("Content-Length")
+	cw.writeHeader()
+
+	cw.encoder = newEncoder(cw.encoding, cw.ResponseWriter)
+	_, err := cw.encoder.Write(cw.buf.Bytes())
+	cw.buf.Reset()
+	return err
+}
+
+func (cw *compressResponseWriter) writeHeader() {
+	if cw.wroteHeader {
+		return
+	}
+	cw.wroteHeader = true
+	if cw.statusCode == 0 {
+		cw.statusCode = http.StatusOK
+	}
+	cw.ResponseWriter.WriteHeader(cw.statusCode)
+}
+
+func (cw *compressResponseWriter) Flush() {
+	if cw.encoder == nil && !cw.passthrough {
+		if cw.compressible() && cw.buf.Len() > 0 {
+			if err := cw.startEncoding(); err != nil {
+				cw.logger.Error().Err(err).Msg("starting response compression failed")
+				return
+			}
+		} else {
+			cw.startPassthrough()
+		}
+	}
+	if f, ok := cw.encoder.(interface{ Flush() error }); ok {
+		if err := f.Flush(); err != nil {
+			cw.logger.Error().Err(err).Msg("flushing compressed response failed")
+			return
+		}
+	}
+	http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// close finishes the response: pending small bodies are written uncompressed
+// and an active encoder is closed to emit its trailer.
+func (cw *compressResponseWriter) close() {
+	if cw.encoder != nil {
+		if err := cw.encoder.Close(); err != nil {
+			cw.logger.Error().Err(err).Msg("closing compressed response failed")
+		}
+		releaseEncoder(cw.encoding, cw.encoder)
+		cw.encoder = nil
+		return
+	}
+	if !cw.passthrough && (cw.statusCode != 0 || cw.buf.Len() > 0) {
+		cw.startPassthrough()
+	}
+}
+
+func (cw *compressResponseWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// NewCompressionMiddleware compresses responses of at least the configured
+// minimum size with the best encoding the client accepts among brotli, zstd
+// and gzip.
+func NewCompressionMiddleware(app news.App) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			acceptEncoding := r.Header.Get("Accept-Encoding")
+			encoding := ""
+			if acceptEncoding != "" && r.Method != http.MethodHead {
+				encoding = negotiate.Best(acceptEncoding, encodings)
+			}
+			if encoding == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressResponseWriter{ResponseWriter: w, encoding: encoding, minSize: app.Config().CompressionMinSize, logger: zerolog.Ctx(r.Context())}
+			defer cw.close()
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
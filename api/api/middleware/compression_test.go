@@ -0,0 +1,302 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"github.com/sunba23/news/config"
+	"github.com/sunba23/news/internal/database"
+)
+
+type testApp struct {
+	conf *config.Config
+}
+
+func (a testApp) Config() *config.Config           { return a.conf }
+func (a testApp) Repository() *database.Repository { return nil }
+func (a testApp) Draining() bool                   { return false }
+func (a testApp) StartDraining()                   {}
+
+func compress(minSize int, h http.HandlerFunc) http.Handler {
+	return NewCompressionMiddleware(testApp{&config.Config{CompressionMinSize: minSize}})(h)
+}
+
+func decode(t *testing.T, encoding string, body []byte) string {
+	t.Helper()
+	var r io.Reader
+	switch encoding {
+	case "":
+		return string(body)
+	case "br":
+		r = brotli.NewReader(bytes.NewReader(body))
+	case "zstd":
+		decoder, err := zstd.NewReader(bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer decoder.Close()
+		r = decoder
+	case "gzip":
+		reader, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = reader
+	default:
+		t.Fatalf("unexpected encoding %q", encoding)
+	}
+	decoded, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("decoding %v failed: %v", encoding, err)
+	}
+	return string(decoded)
+}
+
+func TestCompression(t *testing.T) {
+	large := strings.Repeat("news ", 100)
+
+	tests := []struct {
+		name           string
+		method         string
+		acceptEncoding string
+		handler        http.HandlerFunc
+		wantStatus     int
+		wantEncoding   string
+		wantBody       string
+		// raw compares the body as sent, without decoding it
+		raw bool
+	}{
+		{
+			name:           "brotli is preferred",
+			acceptEncoding: "gzip, zstd, br",
+			handler:        func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, large) },
+			wantEncoding:   "br",
+			wantBody:       large,
+		},
+		{
+			name:           "zstd",
+			acceptEncoding: "zstd",
+			handler:        func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, large) },
+			wantEncoding:   "zstd",
+			wantBody:       large,
+		},
+		{
+			name:           "gzip when others are refused",
+			acceptEncoding: "br;q=0, zstd;q=0, *",
+			handler:        func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, large) },
+			wantEncoding:   "gzip",
+			wantBody:       large,
+		},
+		{
+			name:           "body written in pieces reaching the minimum size",
+			acceptEncoding: "gzip",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				for range 100 {
+					io.WriteString(w, "news ")
+				}
+			},
+			wantEncoding: "gzip",
+			wantBody:     large,
+		},
+		{
+			name:           "small bodies are sent as is",
+			acceptEncoding: "gzip",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusCreated)
+				io.WriteString(w, "small")
+			},
+			wantStatus: http.StatusCreated,
+			wantBody:   "small",
+		},
+		{
+			name:           "no acceptable encoding",
+			acceptEncoding: "gzip;q=0, identity",
+			handler:        func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, large) },
+			wantBody:       large,
+		},
+		{
+			name:     "no Accept-Encoding",
+			handler:  func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, large) },
+			wantBody: large,
+		},
+		{
+			name:           "HEAD",
+			method:         http.MethodHead,
+			acceptEncoding: "gzip",
+			handler:        func(w http.ResponseWriter, r *http.Request) { w.Header().Set("Content-Length", "500") },
+		},
+		{
+			name:           "empty body",
+			acceptEncoding: "gzip",
+			handler:        func(w http.ResponseWriter, r *http.Request) {},
+		},
+		{
+			name:           "no content",
+			acceptEncoding: "gzip",
+			handler:        func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) },
+			wantStatus:     http.StatusNoContent,
+		},
+		{
+			name:           "images",
+			acceptEncoding: "gzip",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "image/png")
+				io.WriteString(w, large)
+			},
+			wantBody: large,
+		},
+		{
+			name:           "already encoded",
+			acceptEncoding: "gzip",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Encoding", "br")
+				io.WriteString(w, large)
+			},
+			// the handler's own encoding is left alone
+			wantEncoding: "br",
+			wantBody:     large,
+			raw:          true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			r := httptest.NewRequest(method, "/news", nil)
+			if tt.acceptEncoding != "" {
+				r.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			rec := httptest.NewRecorder()
+			compress(100, tt.handler).ServeHTTP(rec, r)
+
+			wantStatus := tt.wantStatus
+			if wantStatus == 0 {
+				wantStatus = http.StatusOK
+			}
+			if rec.Code != wantStatus {
+				t.Errorf("status = %v, want %v", rec.Code, wantStatus)
+			}
+			if got := rec.Header().Get("Vary"); got != "Accept-Encoding" {
+				t.Errorf("Vary = %q, want Accept-Encoding", got)
+			}
+			encoding := rec.Header().Get("Content-Encoding")
+			if encoding != tt.wantEncoding {
+				t.Fatalf("Content-Encoding = %q, want %q", encoding, tt.wantEncoding)
+			}
+			body := rec.Body.String()
+			if !tt.raw {
+				if encoding != "" && rec.Header().Get("Content-Length") != "" {
+					t.Error("Content-Length is kept on a compressed response")
+				}
+				body = decode(t, encoding, rec.Body.Bytes())
+			}
+			if got := body; got != tt.wantBody {
+				t.Errorf("body = %q, want %q", got, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestCompressionFlush(t *testing.T) {
+	flushed := make(chan string, 1)
+	handler := compress(1024, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "first event\n")
+		http.NewResponseController(w).Flush()
+		// the small first part is sent compressed rather than held back
+		// until the minimum size is reached
+		flushed <- w.Header().Get("Content-Encoding")
+		io.WriteString(w, "second event\n")
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/stream", nil)
+	r.Header.Set("Accept-Encoding", "zstd")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, r)
+
+	if got := <-flushed; got != "zstd" {
+		t.Errorf("Content-Encoding after Flush = %q, want zstd", got)
+	}
+	if !rec.Flushed {
+		t.Error("Flush did not reach the response writer")
+	}
+	if got, want := decode(t, "zstd", rec.Body.Bytes()), "first event\nsecond event\n"; got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+}
+
+func TestCompressionFlushBeforeWriting(t *testing.T) {
+	handler := compress(1024, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		http.NewResponseController(w).Flush()
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/stream", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, r)
+
+	if rec.Code != http.StatusAccepted || rec.Header().Get("Content-Encoding") != "" || rec.Body.Len() != 0 {
+		t.Errorf("got status %v, encoding %q and body %q, want an empty uncompressed 202",
+			rec.Code, rec.Header().Get("Content-Encoding"), rec.Body.String())
+	}
+}
+
+func TestPooledEncodersAreReset(t *testing.T) {
+	for _, encoding := range encodings {
+		t.Run(encoding, func(t *testing.T) {
+			// a released encoder must not carry anything over to the next
+			// response it compresses
+			for i := range 3 {
+				var out bytes.Buffer
+				e := newEncoder(encoding, &out)
+				want := fmt.Sprintf("response %v", i)
+				if _, err := io.WriteString(e, want); err != nil {
+					t.Fatal(err)
+				}
+				if err := e.Close(); err != nil {
+					t.Fatal(err)
+				}
+				releaseEncoder(encoding, e)
+				if got := decode(t, encoding, out.Bytes()); got != want {
+					t.Errorf("body = %q, want %q", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCompressionConcurrentResponses(t *testing.T) {
+	handler := compress(10, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, strings.Repeat(r.URL.Query().Get("word"), 50))
+	})
+
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			encoding := encodings[i%len(encodings)]
+			word := fmt.Sprintf("word%v-", i)
+			r := httptest.NewRequest(http.MethodGet, "/?word="+word, nil)
+			r.Header.Set("Accept-Encoding", encoding)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, r)
+			if got, want := decode(t, encoding, rec.Body.Bytes()), strings.Repeat(word, 50); got != want {
+				t.Errorf("%v body = %q, want %q", encoding, got, want)
+			}
+		}()
+	}
+	wg.Wait()
+}
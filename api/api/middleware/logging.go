@@ -4,7 +4,7 @@ import (
 	"net/http"
 	"time"
 
-	"github.com/rs/zerolog/log"
+	"github.com/rs/zerolog"
 )
 
 type loggingResponseWriter struct {
@@ -21,21 +21,27 @@ func (lrw *loggingResponseWriter) WriteHeader(code int) {
 	lrw.ResponseWriter.WriteHeader(code)
 }
 
+func (lrw *loggingResponseWriter) Unwrap() http.ResponseWriter {
+	return lrw.ResponseWriter
+}
+
 func LoggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		lrw := NewLoggingResponseWriter(w)
 		start := time.Now()
 
 		defer func() {
+			// panics are recovered by the recovery middleware; the ones
+			// getting here, such as http.ErrAbortHandler, are logged before
+			// passing them on
 			panicVal := recover()
 			if panicVal != nil {
 				lrw.statusCode = http.StatusInternalServerError
-				panic(panicVal)
 			}
 
 			duration_sec := time.Since(start).Seconds()
-			log.Info().Ctx(r.Context()).
-				Str("method", r.Method).
+			// the request logger carries the request id, method and route
+			zerolog.Ctx(r.Context()).Info().
 				Stringer("path", r.URL).
 				Int("status_code", lrw.statusCode).
 				Str("host", r.Host).
@@ -44,6 +50,10 @@ func LoggingMiddleware(next http.Handler) http.Handler {
 				Str("referer", r.Referer()).
 				Float64("duration_sec", duration_sec).
 				Msg("Access log")
+
+			if panicVal != nil {
+				panic(panicVal)
+			}
 		}()
 
 		next.ServeHTTP(lrw, r)
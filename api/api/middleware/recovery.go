@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+	"github.com/sunba23/news/constants"
+	"github.com/sunba23/news/internal/errorreport"
+)
+
+// panicsTotal counts the recovered panics per route, published on
+// /debug/vars of the admin listener.
+var panicsTotal = expvar.NewMap("http_panics_total")
+
+// problem is an RFC 9457 problem details response.
+type problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Instance  string `json:"instance,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+type recoveryResponseWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (rw *recoveryResponseWriter) WriteHeader(code int) {
+	rw.wroteHeader = true
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *recoveryResponseWriter) Write(b []byte) (int, error) {
+	rw.wroteHeader = true
+	return rw.ResponseWriter.Write(b)
+}
+
+func (rw *recoveryResponseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// NewRecoveryMiddleware recovers panics in later handlers: it logs the stack
+// with the request, counts the panic, forwards it to reporter unless nil and
+// answers with a problem details 500 if the response has not started yet.
+// http.ErrAbortHandler is passed on, as it aborts the response on purpose.
+func NewRecoveryMiddleware(reporter errorreport.Reporter) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := &recoveryResponseWriter{ResponseWriter: w}
+			defer func() {
+				panicVal := recover()
+				if panicVal == nil {
+					return
+				}
+				if panicVal == http.ErrAbortHandler {
+					panic(panicVal)
+				}
+
+				stack := debug.Stack()
+				message := fmt.Sprint(panicVal)
+				requestID, _ := r.Context().Value(constants.RequestIdContextKey).(string)
+				userID, _ := r.Context().Value(constants.UserIdContextKey).(string)
+				route := ""
+				if current := mux.CurrentRoute(r); current != nil {
+					route, _ = current.GetPathTemplate()
+				}
+
+				zerolog.Ctx(r.Context()).Error().
+					Str("panic", message).
+					Str("stack", string(stack)).
+					Msg("Recovered from panic")
+				panicsTotal.Add(route, 1)
+				if reporter != nil {
+					reporter.Report(r.Context(), errorreport.Report{
+						Time:      time.Now(),
+						Message:   message,
+						Stack:     stack,
+						RequestID: requestID,
+						Method:    r.Method,
+						URL:       r.URL.String(),
+						Route:     route,
+						UserID:    userID,
+					})
+				}
+
+				if rw.wroteHeader {
+					return
+				}
+				w.Header().Set("Content-Type", "application/problem+json")
+				w.Header().Del("Content-Length")
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(problem{
+					Type:      "about:blank",
+					Title:     http.StatusText(http.StatusInternalServerError),
+					Status:    http.StatusInternalServerError,
+					Instance:  r.URL.Path,
+					RequestID: requestID,
+				})
+			}()
+
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
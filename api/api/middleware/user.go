@@ -8,7 +8,7 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/gorilla/sessions"
-	"github.com/rs/zerolog/log"
+	"github.com/rs/zerolog"
 	"github.com/sunba23/news/constants"
 	"github.com/sunba23/news/internal/news"
 )
@@ -18,7 +18,7 @@ func NewUserContextMiddleware(store *sessions.CookieStore, app news.App) mux.Mid
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			session, err := store.Get(r, "news-session")
 			if err != nil {
-				log.Warn().Err(err).Msg("Session error")
+				zerolog.Ctx(r.Context()).Warn().Err(err).Msg("Session error")
 				next.ServeHTTP(w, r)
 				return
 			}
@@ -42,12 +42,21 @@ func NewUserContextMiddleware(store *sessions.CookieStore, app news.App) mux.Mid
 			repository := *app.Repository()
 			user, err := repository.GetUserByGoogleID(r.Context(), userID)
 			if err != nil {
-				log.Error().Err(err).Msg(fmt.Sprintf("Failed to load user with id %v", userID))
+				zerolog.Ctx(r.Context()).Error().Err(err).Msg(fmt.Sprintf("Failed to load user with id %v", userID))
+				next.ServeHTTP(w, r)
+				return
+			}
+			// sessions of deleted users and of users who asked to be deleted
+			// are no longer valid
+			if user == nil || user.DeletionRequestedAt != nil {
 				next.ServeHTTP(w, r)
 				return
 			}
 
 			ctx := context.WithValue(r.Context(), constants.UserIdContextKey, user.ID)
+			ctx = context.WithValue(ctx, constants.UserPreferencesContextKey, user.Preferences)
+			logger := zerolog.Ctx(ctx).With().Str("user_id", user.ID).Logger()
+			ctx = logger.WithContext(ctx)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
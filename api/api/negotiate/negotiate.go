@@ -0,0 +1,85 @@
+// Package negotiate implements the quality-value matching shared by Accept and
+// Accept-Encoding negotiation.
+package negotiate
+
+import (
+	"strconv"
+	"strings"
+)
+
+type spec struct {
+	value string
+	q     float64
+}
+
+func parse(header string) []spec {
+	var specs []spec
+	for part := range strings.SplitSeq(header, ",") {
+		value, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value == "" {
+			continue
+		}
+
+		q := 1.0
+		for param := range strings.SplitSeq(params, ";") {
+			key, val, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if !ok || strings.ToLower(strings.TrimSpace(key)) != "q" {
+				continue
+			}
+			if parsed, err := strconv.ParseFloat(strings.TrimSpace(val), 64); err == nil {
+				q = parsed
+			}
+		}
+		specs = append(specs, spec{value: value, q: q})
+	}
+	return specs
+}
+
+// quality returns the q-value the client assigned to offer, preferring exact
+// matches over media range (type/*) matches over wildcards.
+func quality(specs []spec, offer string) float64 {
+	offer = strings.ToLower(offer)
+	mediaRange := ""
+	if typ, _, ok := strings.Cut(offer, "/"); ok {
+		mediaRange = typ + "/*"
+	}
+
+	best, bestRank := 0.0, -1
+	for _, s := range specs {
+		rank := -1
+		switch s.value {
+		case offer:
+			rank = 2
+		case mediaRange:
+			rank = 1
+		case "*", "*/*":
+			rank = 0
+		}
+		if rank > bestRank {
+			best, bestRank = s.q, rank
+		}
+	}
+	return best
+}
+
+// Best returns the offer the client accepts with the highest quality, ties
+// going to the earlier offer. An empty header accepts the first offer. It
+// returns "" when none of the offers is acceptable.
+func Best(header string, offers []string) string {
+	if len(offers) == 0 {
+		return ""
+	}
+	specs := parse(header)
+	if len(specs) == 0 {
+		return offers[0]
+	}
+
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		if q := quality(specs, offer); q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
@@ -0,0 +1,47 @@
+package negotiate
+
+import "testing"
+
+func TestBest(t *testing.T) {
+	encodings := []string{"br", "zstd", "gzip"}
+	mediaTypes := []string{"application/json", "application/x-ndjson", "text/csv"}
+
+	tests := []struct {
+		header string
+		offers []string
+		want   string
+	}{
+		// an empty header accepts anything
+		{"", encodings, "br"},
+		{"gzip", encodings, "gzip"},
+		// equal qualities go to the earlier offer
+		{"gzip, zstd", encodings, "zstd"},
+		{"br;q=0.5, gzip;q=0.8", encodings, "gzip"},
+		{"GZIP;Q=1", encodings, "gzip"},
+		{" gzip ; q=0.9 ,, zstd;q=0.1", encodings, "gzip"},
+		// an unparsable quality counts as 1
+		{"gzip;q=high, br;q=0.5", encodings, "gzip"},
+		// q=0 refuses an offer, even when a wildcard accepts the others
+		{"br;q=0, *", encodings, "zstd"},
+		{"*;q=0", encodings, ""},
+		{"*;q=0, gzip", encodings, "gzip"},
+		{"identity", encodings, ""},
+		{"gzip;q=0", encodings, ""},
+		{"*", encodings, "br"},
+
+		{"*/*", mediaTypes, "application/json"},
+		{"text/csv", mediaTypes, "text/csv"},
+		{"text/*", mediaTypes, "text/csv"},
+		// exact matches take precedence over media ranges and wildcards
+		{"application/*;q=0.2, application/x-ndjson, */*;q=0.1", mediaTypes, "application/x-ndjson"},
+		{"application/json;q=0, */*", mediaTypes, "application/x-ndjson"},
+		{"application/*;q=0, text/csv;q=0.5", mediaTypes, "text/csv"},
+		{"image/png", mediaTypes, ""},
+		{"text/csv", nil, ""},
+	}
+	for _, tt := range tests {
+		if got := Best(tt.header, tt.offers); got != tt.want {
+			t.Errorf("Best(%q, %v) = %q, want %q", tt.header, tt.offers, got, tt.want)
+		}
+	}
+}
@@ -1,50 +1,131 @@
 package api
 
 import (
+	"expvar"
 	"net/http"
+	"net/http/pprof"
 
 	"github.com/gorilla/mux"
 	"github.com/sunba23/news/api/handler"
 	"github.com/sunba23/news/api/middleware"
+	"github.com/sunba23/news/internal/audit"
+	"github.com/sunba23/news/internal/errorreport"
 	"github.com/sunba23/news/internal/news"
+	"github.com/sunba23/news/internal/recommend"
+	"github.com/sunba23/news/internal/trends"
 )
 
-func NewHttpHandler(app news.App) http.Handler {
+func NewHttpHandler(app news.App, tracker *trends.Tracker, reporter errorreport.Reporter) http.Handler {
 	router := mux.NewRouter()
 
-	authHandler := handler.NewAuthHandler(app)
-	newsHandler := handler.NewsHandler{App: app}
-	tagsHandler := handler.TagsHandler{App: app}
-	userHandler := handler.UserHandler{App: app}
+	auditor := audit.NewAuditor(*app.Repository())
+	authHandler := handler.NewAuthHandler(app, auditor)
+	recommender := recommend.NewRecommender(*app.Repository())
+	newsHandler := handler.NewsHandler{App: app, Recommender: recommender}
+	tagsHandler := handler.TagsHandler{App: app, Auditor: auditor}
+	userHandler := handler.UserHandler{App: app, Recommender: recommender, SessionStore: authHandler.SessionStore, Auditor: auditor}
+	storiesHandler := handler.StoriesHandler{App: app}
+	searchesHandler := handler.SearchesHandler{App: app, Auditor: auditor}
+	trendsHandler := handler.TrendsHandler{App: app, Tracker: tracker}
+	healthHandler := handler.HealthHandler{App: app}
 
 	authenticationMiddleware := middleware.NewAuthenticationMiddleware()
 	userContextMiddleware := middleware.NewUserContextMiddleware(authHandler.SessionStore, app)
 
-	router.Use(middleware.LoggingMiddleware, userContextMiddleware)
+	compressionMiddleware := middleware.NewCompressionMiddleware(app)
+
+	recoveryMiddleware := middleware.NewRecoveryMiddleware(reporter)
+
+	router.Use(middleware.RequestIDMiddleware, middleware.LoggingMiddleware, compressionMiddleware, recoveryMiddleware, userContextMiddleware)
 	router.HandleFunc("/", handler.HandleRoot)
+	router.HandleFunc("/healthz", healthHandler.HandleLiveness).Methods(http.MethodGet)
+	router.HandleFunc("/readyz", healthHandler.HandleReadiness).Methods(http.MethodGet)
+	router.HandleFunc("/version", healthHandler.HandleVersion).Methods(http.MethodGet)
 
 	authSubRouter := router.PathPrefix("/auth/google").Subrouter()
 	authSubRouter.HandleFunc("/login", authHandler.HandleGoogleLogin)
 	authSubRouter.HandleFunc("/callback", authHandler.HandleGoogleCallback)
 	authSubRouter.HandleFunc("/logout", authHandler.HandleLogout)
 
+	router.Handle("/news:batchGet", authenticationMiddleware(http.HandlerFunc(newsHandler.HandleBatchGetNews))).Methods(http.MethodPost)
+
 	newsSubRouter := router.PathPrefix("/news").Subrouter()
 	newsSubRouter.HandleFunc("", newsHandler.HandleGetAllNews).Methods(http.MethodGet)
 	newsSubRouter.HandleFunc("/{id:[0-9]+}", newsHandler.HandleGetNewsById).Methods(http.MethodGet)
 	newsSubRouter.HandleFunc("/{id:[0-9]+}/tags", newsHandler.HandleGetTagsForNews).Methods(http.MethodGet)
+	newsSubRouter.HandleFunc("/{id:[0-9]+}/related", newsHandler.HandleGetRelatedNews).Methods(http.MethodGet)
 	newsSubRouter.Use(authenticationMiddleware)
 
 	tagsSubRouter := router.PathPrefix("/tags").Subrouter()
 	tagsSubRouter.HandleFunc("", tagsHandler.HandleGetAllTags).Methods(http.MethodGet)
-	tagsSubRouter.HandleFunc("/{id:[0-9]+}/news", tagsHandler.HandleGetNewsByTag).Methods(http.MethodGet)
+	tagsSubRouter.HandleFunc("/tree", tagsHandler.HandleGetTagTree).Methods(http.MethodGet)
+	tagsSubRouter.HandleFunc("/{tag}", tagsHandler.HandleGetTag).Methods(http.MethodGet)
+	tagsSubRouter.HandleFunc("/{tag}/news", tagsHandler.HandleGetNewsByTag).Methods(http.MethodGet)
 	tagsSubRouter.Use(authenticationMiddleware)
 
+	storiesSubRouter := router.PathPrefix("/stories").Subrouter()
+	storiesSubRouter.HandleFunc("/{id:[0-9]+}", storiesHandler.HandleGetStory).Methods(http.MethodGet)
+	storiesSubRouter.Use(authenticationMiddleware)
+
+	trendsSubRouter := router.PathPrefix("/trends").Subrouter()
+	trendsSubRouter.HandleFunc("/tags", trendsHandler.HandleGetTagTrends).Methods(http.MethodGet)
+	trendsSubRouter.HandleFunc("/news", trendsHandler.HandleGetNewsTrends).Methods(http.MethodGet)
+	trendsSubRouter.Use(authenticationMiddleware)
+
 	userSubRouter := router.PathPrefix("/user").Subrouter()
+	userSubRouter.HandleFunc("", userHandler.HandleGetProfile).Methods(http.MethodGet)
+	userSubRouter.HandleFunc("", userHandler.HandleDeleteAccount).Methods(http.MethodDelete)
+	userSubRouter.HandleFunc("/export", userHandler.HandleExport).Methods(http.MethodGet)
+	userSubRouter.HandleFunc("/preferences", userHandler.HandleUpdatePreferences).Methods(http.MethodPatch)
 	userSubRouter.HandleFunc("/tags", userHandler.HandleGetFavoriteTags).Methods(http.MethodGet)
-	userSubRouter.HandleFunc("/tags/{id:[0-9]+}", userHandler.HandleAddFavoriteTag).Methods(http.MethodPost)
-	userSubRouter.HandleFunc("/tags/{id:[0-9]+}", userHandler.HandleDeleteFavoriteTag).Methods(http.MethodDelete)
+	userSubRouter.HandleFunc("/tags/{tag}", userHandler.HandleAddFavoriteTag).Methods(http.MethodPost)
+	userSubRouter.HandleFunc("/tags/{tag}", userHandler.HandleDeleteFavoriteTag).Methods(http.MethodDelete)
 	userSubRouter.HandleFunc("/news", userHandler.HandleGetFavoriteNews).Methods(http.MethodGet)
+	userSubRouter.HandleFunc("/mutes", userHandler.HandleGetMutes).Methods(http.MethodGet)
+	userSubRouter.HandleFunc("/mutes/{kind:tags}/{tag}", userHandler.HandleAddMute).Methods(http.MethodPost)
+	userSubRouter.HandleFunc("/mutes/{kind:tags}/{tag}", userHandler.HandleDeleteMute).Methods(http.MethodDelete)
+	userSubRouter.HandleFunc("/mutes/{kind:authors|sources|keywords}/{value}", userHandler.HandleAddMute).Methods(http.MethodPost)
+	userSubRouter.HandleFunc("/mutes/{kind:authors|sources|keywords}/{value}", userHandler.HandleDeleteMute).Methods(http.MethodDelete)
+	userSubRouter.HandleFunc("/searches", searchesHandler.HandleGetSearches).Methods(http.MethodGet)
+	userSubRouter.HandleFunc("/searches", searchesHandler.HandleCreateSearch).Methods(http.MethodPost)
+	userSubRouter.HandleFunc("/searches/{id:[0-9]+}", searchesHandler.HandleGetSearch).Methods(http.MethodGet)
+	userSubRouter.HandleFunc("/searches/{id:[0-9]+}", searchesHandler.HandleDeleteSearch).Methods(http.MethodDelete)
+	userSubRouter.HandleFunc("/searches/{id:[0-9]+}/results", searchesHandler.HandleGetSearchResults).Methods(http.MethodGet)
+	userSubRouter.HandleFunc("/searches/{id:[0-9]+}/matches", searchesHandler.HandleGetSearchMatches).Methods(http.MethodGet)
+	userSubRouter.HandleFunc("/recommendations", userHandler.HandleGetRecommendations).Methods(http.MethodGet)
+	userSubRouter.HandleFunc("/bookmarks", userHandler.HandleGetBookmarks).Methods(http.MethodGet)
+	userSubRouter.HandleFunc("/bookmarks/{id:[0-9]+}", userHandler.HandleAddBookmark).Methods(http.MethodPost)
+	userSubRouter.HandleFunc("/bookmarks/{id:[0-9]+}", userHandler.HandleDeleteBookmark).Methods(http.MethodDelete)
 	userSubRouter.Use(authenticationMiddleware)
 
 	return router
 }
+
+func NewAdminHttpHandler(app news.App, reporter errorreport.Reporter) http.Handler {
+	router := mux.NewRouter()
+	router.Use(middleware.RequestIDMiddleware, middleware.LoggingMiddleware, middleware.NewRecoveryMiddleware(reporter))
+	router.Use(middleware.ClientCertificateMiddleware)
+
+	router.Handle("/debug/vars", expvar.Handler())
+
+	debugSubRouter := router.PathPrefix("/debug/pprof").Subrouter()
+	debugSubRouter.HandleFunc("/cmdline", pprof.Cmdline)
+	debugSubRouter.HandleFunc("/profile", pprof.Profile)
+	debugSubRouter.HandleFunc("/symbol", pprof.Symbol)
+	debugSubRouter.HandleFunc("/trace", pprof.Trace)
+	debugSubRouter.PathPrefix("/").HandlerFunc(pprof.Index)
+
+	adminSubRouter := router.PathPrefix("/admin").Subrouter()
+
+	auditor := audit.NewAuditor(*app.Repository())
+	tagsHandler := handler.TagsHandler{App: app, Auditor: auditor}
+	tagsSubRouter := adminSubRouter.PathPrefix("/tags").Subrouter()
+	tagsSubRouter.HandleFunc("/{tag}/parent", tagsHandler.HandleSetTagParent).Methods(http.MethodPut)
+	tagsSubRouter.HandleFunc("/{tag}/aliases", tagsHandler.HandleAddTagAlias).Methods(http.MethodPost)
+	tagsSubRouter.HandleFunc("/aliases/{alias}", tagsHandler.HandleDeleteTagAlias).Methods(http.MethodDelete)
+
+	auditHandler := handler.AuditHandler{App: app}
+	adminSubRouter.HandleFunc("/audit", auditHandler.HandleGetAuditEvents).Methods(http.MethodGet)
+
+	return router
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sunba23/news/config"
+	"gopkg.in/yaml.v3"
+)
+
+// RunConfig runs the configuration subcommands. Unlike the other commands,
+// it runs before the configuration is validated.
+func RunConfig(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing config subcommand, expected print")
+	}
+	switch subcommand := args[0]; subcommand {
+	case "print":
+		return runConfigPrint(os.Stdout, args[1:])
+	default:
+		return fmt.Errorf("unknown config subcommand %q, expected print", subcommand)
+	}
+}
+
+// runConfigPrint writes the effective configuration as a config file, noting
+// for every setting its environment variable and where its value comes from,
+// followed by the problems found validating it.
+func runConfigPrint(w io.Writer, args []string) error {
+	flags := flag.NewFlagSet("config print", flag.ContinueOnError)
+	redact := flags.Bool("redact", false, "mask secrets and the credentials in connection URLs")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	config.LoadDotEnv()
+	conf, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err = encoder.Encode(settingsDocument(conf.Settings(*redact))); err != nil {
+		return err
+	}
+	if err = encoder.Close(); err != nil {
+		return err
+	}
+
+	var validationErr *config.ValidationError
+	if err = conf.Validate(); errors.As(err, &validationErr) {
+		fmt.Fprintln(os.Stderr, validationErr.Error())
+		return errors.New("the configuration is invalid")
+	}
+	return err
+}
+
+// settingsDocument nests the settings in sections by their path, e.g.
+// server.host as host in server.
+func settingsDocument(settings []config.Setting) *yaml.Node {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	for _, setting := range settings {
+		sections := strings.Split(setting.Path, ".")
+		parent := root
+		for _, section := range sections[:len(sections)-1] {
+			parent = sectionNode(parent, section)
+		}
+
+		value := setting.Value
+		if d, ok := value.(time.Duration); ok {
+			value = d.String()
+		}
+		valueNode := &yaml.Node{}
+		if err := valueNode.Encode(value); err != nil {
+			valueNode = &yaml.Node{Kind: yaml.ScalarNode, Value: fmt.Sprint(value)}
+		}
+		keyNode := &yaml.Node{Kind: yaml.ScalarNode, Value: sections[len(sections)-1]}
+		comment := fmt.Sprintf("%s, %s", setting.Key, setting.Source)
+		if valueNode.Kind == yaml.SequenceNode && len(valueNode.Content) > 0 {
+			keyNode.LineComment = comment
+		} else {
+			valueNode.LineComment = comment
+		}
+		parent.Content = append(parent.Content, keyNode, valueNode)
+	}
+	return root
+}
+
+// sectionNode returns the mapping of the named section in parent, adding it
+// when missing.
+func sectionNode(parent *yaml.Node, name string) *yaml.Node {
+	for i := 0; i < len(parent.Content); i += 2 {
+		if parent.Content[i].Value == name {
+			return parent.Content[i+1]
+		}
+	}
+	section := &yaml.Node{Kind: yaml.MappingNode}
+	parent.Content = append(parent.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: name}, section)
+	return section
+}
@@ -31,6 +31,9 @@ func SetUpLogging(cnf *config.Config) {
 	}
 
 	log.Logger = logger
+	// loggers are taken from the context, falling back to the global one
+	// outside requests
+	zerolog.DefaultContextLogger = &log.Logger
 }
 
 func setGlobalLevel(level string) {
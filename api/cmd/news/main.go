@@ -1,6 +1,15 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	_ "time/tzdata"
+
 	"github.com/k0kubun/pp/v3"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
@@ -9,6 +18,30 @@ import (
 )
 
 func main() {
+	configFile := flag.String("config", "", "YAML or TOML config file")
+	storage := flag.String("storage", "", "storage backend: sql (PostgreSQL or SQLite, see DATABASE_URL) or memory (with seed data); postgres is an alias of sql")
+	flag.Func("set", "set a config value, e.g. --set logging.level=info (repeatable)", func(value string) error {
+		key, value, ok := strings.Cut(value, "=")
+		if !ok {
+			return fmt.Errorf("expected key=value")
+		}
+		return config.Override(key, value)
+	})
+	flag.Parse()
+	config.SetConfigFile(*configFile)
+	if *storage != "" {
+		if err := config.Override("STORAGE", *storage); err != nil {
+			log.Fatal().Err(err).Send()
+		}
+	}
+
+	if flag.Arg(0) == "config" {
+		if err := RunConfig(flag.Args()[1:]); err != nil {
+			log.Fatal().Err(err).Send()
+		}
+		return
+	}
+
 	conf, err := config.InitConfig()
 	if err != nil {
 		log.Fatal().Err(err).Send()
@@ -22,13 +55,28 @@ func main() {
 	}
 
 	app, err := news.NewApplication(conf)
-
 	if err != nil {
 		log.Fatal().Err(err).Send()
 	}
 
-	err = RunServer(app)
-	if err != nil {
-		log.Fatal().Err(err).Send()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	switch command := flag.Arg(0); command {
+	case "", "serve":
+		if err := RunServer(ctx, app); err != nil {
+			log.Fatal().Err(err).Send()
+		}
+		log.Info().Msg("Shutdown complete")
+	case "retag":
+		if err := RunRetag(ctx, app, flag.Args()[1:]); err != nil {
+			log.Fatal().Err(err).Send()
+		}
+	case "users":
+		if err := RunUsers(ctx, app, flag.Args()[1:]); err != nil {
+			log.Fatal().Err(err).Send()
+		}
+	default:
+		log.Fatal().Str("command", command).Msg("unknown command, expected serve, retag, users or config")
 	}
 }
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/rs/zerolog/log"
+	"github.com/sunba23/news/config"
+	"github.com/sunba23/news/internal/news"
+)
+
+// reloadDelay lets editors finish writing the config file, which often
+// takes several writes or a rename, before it is read.
+const reloadDelay = 250 * time.Millisecond
+
+// configReloader reads the configuration again whenever the config file
+// changes or the process receives SIGHUP. The new configuration replaces the
+// current one only when it is valid and all the settings that changed can
+// change at runtime; the others require a restart.
+type configReloader struct {
+	app  *news.Application
+	file string
+}
+
+func newConfigReloader(app *news.Application, file string) (*configReloader, error) {
+	if file != "" {
+		var err error
+		if file, err = filepath.Abs(file); err != nil {
+			return nil, err
+		}
+	}
+	return &configReloader{app: app, file: file}, nil
+}
+
+// Run reloads the configuration until ctx is cancelled.
+func (cr *configReloader) Run(ctx context.Context) error {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	var events <-chan fsnotify.Event
+	var watchErrors <-chan error
+	if cr.file != "" {
+		watcher, err := fsnotify.NewWatcher()
+		if err != nil {
+			return err
+		}
+		defer watcher.Close()
+		// the directory is watched, as the file may be replaced rather than
+		// written to
+		if err = watcher.Add(filepath.Dir(cr.file)); err != nil {
+			return err
+		}
+		events, watchErrors = watcher.Events, watcher.Errors
+	}
+
+	var changed <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-hangup:
+			cr.reload("SIGHUP")
+		case event := <-events:
+			if event.Name == cr.file && event.Has(fsnotify.Write|fsnotify.Create|fsnotify.Rename) {
+				changed = time.After(reloadDelay)
+			}
+		case <-changed:
+			changed = nil
+			cr.reload("config file change")
+		case err := <-watchErrors:
+			log.Error().Err(err).Str("file", cr.file).Msg("watching the config file failed")
+		}
+	}
+}
+
+func (cr *configReloader) reload(trigger string) {
+	logger := log.With().Str("trigger", trigger).Logger()
+
+	next, err := config.NewConfig()
+	if err != nil {
+		logger.Error().Err(err).Msg("reloading the configuration failed, keeping the current one")
+		return
+	}
+
+	changes := cr.app.Config().Changes(next)
+	var restartRequired []string
+	for _, change := range changes {
+		if !change.Reloadable {
+			restartRequired = append(restartRequired, change.Key)
+		}
+	}
+	if len(restartRequired) > 0 {
+		logger.Error().
+			Strs("settings", restartRequired).
+			Msg("reloading the configuration failed, changing these settings requires a restart; keeping the current configuration")
+		return
+	}
+
+	cr.app.SetConfig(next)
+	for _, change := range changes {
+		logger.Info().
+			Str("setting", change.Key).
+			Interface("old", change.Old).
+			Interface("new", change.New).
+			Msg("configuration setting changed")
+	}
+	logger.Info().Int("changes", len(changes)).Msg("configuration reloaded")
+	// last, so that the changes are logged at the previous level
+	setGlobalLevel(next.LoggingLevel)
+}
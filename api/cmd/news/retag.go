@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sunba23/news/internal/audit"
+	"github.com/sunba23/news/internal/database"
+	"github.com/sunba23/news/internal/news"
+	"github.com/sunba23/news/internal/tagging"
+)
+
+// RunRetag backfills inferred tags on existing news. Tags already set keep the
+// higher confidence.
+func RunRetag(ctx context.Context, app *news.Application, args []string) error {
+	flags := flag.NewFlagSet("retag", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "log the inferred tags without storing them")
+	since := flags.String("since", "", "only retag news created on or after this date (YYYY-MM-DD)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var filter database.NewsFilter
+	if *since != "" {
+		t, err := time.Parse(time.DateOnly, *since)
+		if err != nil {
+			return fmt.Errorf("invalid --since %q, expected YYYY-MM-DD", *since)
+		}
+		filter.Since = &t
+	}
+
+	conf := app.Config()
+	rules, err := tagging.LoadRules(conf.TaggingRulesFile)
+	if err != nil {
+		return err
+	}
+
+	return app.RunTask(ctx, func(ctx context.Context) error {
+		repository := *app.Repository()
+		tags, err := repository.GetAllTags(ctx)
+		if err != nil {
+			return err
+		}
+		aliases, err := repository.GetTagAliases(ctx)
+		if err != nil {
+			return err
+		}
+		engine, err := tagging.NewEngine(tags, rules.WithAliases(tags, aliases), conf.TaggingMinConfidence)
+		if err != nil {
+			return err
+		}
+
+		all, err := repository.SearchNews(ctx, filter)
+		if err != nil {
+			return err
+		}
+
+		var tagged, assigned int
+		if !*dryRun {
+			// also when interrupted, as the news tagged so far stay changed
+			defer func() {
+				audit.NewAuditor(repository).Add(context.WithoutCancel(ctx), database.AuditEvent{
+					ActorID: audit.ActorSystem, Action: audit.ActionRetag, TargetType: audit.TargetNews, TargetID: strconv.Itoa(tagged),
+				})
+			}()
+		}
+		for _, n := range all {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			assignments := engine.Tag(n)
+			if len(assignments) == 0 {
+				continue
+			}
+			log.Debug().Int("news_id", n.ID).Interface("tags", assignments).Msg("inferred tags")
+			if !*dryRun {
+				if err := repository.AddTagsToNews(ctx, n.ID, assignments); err != nil {
+					return fmt.Errorf("tagging news %v failed: %w", n.ID, err)
+				}
+			}
+			tagged++
+			assigned += len(assignments)
+		}
+		log.Info().Int("news", len(all)).Int("tagged", tagged).Int("tags", assigned).Bool("dry_run", *dryRun).Msg("Retag complete")
+		return nil
+	})
+}
@@ -2,47 +2,94 @@ package main
 
 import (
 	"context"
-	"fmt"
 	"net/http"
-	"os"
-	"os/signal"
-	"time"
 
-	"github.com/rs/zerolog/log"
 	"github.com/sunba23/news/api"
+	"github.com/sunba23/news/config"
+	"github.com/sunba23/news/internal/errorreport"
 	"github.com/sunba23/news/internal/news"
+	"github.com/sunba23/news/internal/searches"
+	"github.com/sunba23/news/internal/stories"
+	"github.com/sunba23/news/internal/trends"
 )
 
-func RunServer(app news.App) error {
+// RunServer registers the HTTP listeners with the application lifecycle and
+// runs it until ctx is cancelled.
+func RunServer(ctx context.Context, app *news.Application) error {
 	conf := app.Config()
 
-	handler := api.NewHttpHandler(app)
-	server := &http.Server{
-		Addr:        conf.ServerHost,
-		ReadTimeout: time.Second * time.Duration(conf.ServerReadTimeoutSeconds),
-		Handler:     handler,
+	tlsConfig, acmeHandler, err := NewTLSConfig(conf)
+	if err != nil {
+		return err
 	}
 
-	go func() {
-		log.Info().Msg(fmt.Sprintf("Starting server available at %v", conf.ServerHost))
-		if err := server.ListenAndServe(); err != nil {
-			log.Error().Err(err).Send()
+	reloader, err := newConfigReloader(app, config.ConfigFile())
+	if err != nil {
+		return err
+	}
+	app.Register(news.NewWorker("config", reloader.Run))
+
+	clusterer := stories.NewClusterer(
+		*app.Repository(),
+		conf.StoriesClusterInterval,
+		conf.StoriesMaxDistance,
+	)
+	app.Register(news.NewWorker("stories", clusterer.Run))
+
+	tracker := trends.NewTracker(*app.Repository(), conf.TrendsRefreshInterval)
+	app.Register(news.NewWorker("trends", tracker.Run))
+
+	matcher := searches.NewMatcher(*app.Repository(), conf.SearchesMatchInterval)
+	app.Register(news.NewWorker("searches", matcher.Run))
+
+	// a nil interface, not a nil *SentryReporter, disables reporting
+	var reporter errorreport.Reporter
+	if conf.ErrorReportingDSN != "" {
+		sentry, err := errorreport.NewSentryReporter(conf.ErrorReportingDSN)
+		if err != nil {
+			return err
 		}
-	}()
+		app.Register(news.NewWorker("errorreport", sentry.Run))
+		reporter = sentry
+	}
 
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
-	<-c
+	handler := api.NewHttpHandler(app, tracker, reporter)
+	app.Register(news.NewHTTPServerComponent("api", &http.Server{
+		Addr:        conf.ServerHost,
+		ReadTimeout: conf.ServerReadTimeout,
+		Handler:     handler,
+		TLSConfig:   tlsConfig,
+		Protocols:   serverProtocols(conf.ServerHTTP2),
+	}))
 
-	log.Info().Msg("Received interrupt. Cleaning up...")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	if tlsConfig != nil && conf.TLSRedirectHost != "" {
+		app.Register(news.NewHTTPServerComponent("redirect", &http.Server{
+			Addr:        conf.TLSRedirectHost,
+			ReadTimeout: conf.ServerReadTimeout,
+			Handler:     acmeHandler(redirectToHTTPS(conf.ServerHost)),
+		}))
+	}
 
-	err := server.Shutdown(ctx)
-	if err != nil {
-		log.Fatal().Err(err).Send()
+	if conf.AdminHost != "" {
+		adminTLSConfig, err := NewAdminTLSConfig(conf, tlsConfig)
+		if err != nil {
+			return err
+		}
+		app.Register(news.NewHTTPServerComponent("admin", &http.Server{
+			Addr:        conf.AdminHost,
+			ReadTimeout: conf.ServerReadTimeout,
+			Handler:     api.NewAdminHttpHandler(app, reporter),
+			TLSConfig:   adminTLSConfig,
+			Protocols:   serverProtocols(conf.ServerHTTP2),
+		}))
 	}
 
-	os.Exit(0)
-	return nil
+	return app.Run(ctx)
+}
+
+func serverProtocols(http2 bool) *http.Protocols {
+	protocols := &http.Protocols{}
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(http2)
+	return protocols
 }
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sunba23/news/config"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// certReloader serves a certificate loaded from disk and reloads it from the
+// same files whenever the process receives SIGHUP.
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+}
+
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	cr := &certReloader{certFile: certFile, keyFile: keyFile}
+	if err := cr.reload(); err != nil {
+		return nil, err
+	}
+
+	// register before returning, so that a SIGHUP right after startup is
+	// not fatal
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGHUP)
+	go func() {
+		for range c {
+			if err := cr.reload(); err != nil {
+				log.Error().Err(err).Msg("reloading TLS certificate failed, keeping the previous one")
+				continue
+			}
+			log.Info().Str("cert_file", certFile).Msg("TLS certificate reloaded")
+		}
+	}()
+
+	return cr, nil
+}
+
+func (cr *certReloader) reload() error {
+	cert, err := tls.LoadX509KeyPair(cr.certFile, cr.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS key pair: %w", err)
+	}
+
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	cr.cert = &cert
+	return nil
+}
+
+func (cr *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cr.mu.RLock()
+	defer cr.mu.RUnlock()
+	return cr.cert, nil
+}
+
+// NewTLSConfig builds the TLS configuration for the public listener. It
+// returns a nil config when TLS is disabled. When ACME is enabled the returned
+// handler wrapper answers HTTP-01 challenges and must be installed on the plain
+// HTTP listener.
+func NewTLSConfig(conf *config.Config) (*tls.Config, func(http.Handler) http.Handler, error) {
+	passthrough := func(h http.Handler) http.Handler { return h }
+
+	if conf.TLSAcmeEnabled {
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			Cache:      autocert.DirCache(conf.TLSAcmeCacheDir),
+			HostPolicy: autocert.HostWhitelist(conf.TLSAcmeDomains...),
+			Email:      conf.TLSAcmeEmail,
+		}
+		tlsConfig := &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: manager.GetCertificate,
+			NextProtos:     append(nextProtos(conf), acme.ALPNProto),
+		}
+		return tlsConfig, manager.HTTPHandler, nil
+	}
+
+	if conf.TLSCertFile != "" {
+		reloader, err := newCertReloader(conf.TLSCertFile, conf.TLSKeyFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		tlsConfig := &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: reloader.GetCertificate,
+			NextProtos:     nextProtos(conf),
+		}
+		return tlsConfig, passthrough, nil
+	}
+
+	return nil, passthrough, nil
+}
+
+// NewAdminTLSConfig derives the admin listener TLS configuration from the
+// public one, additionally requiring client certificates signed by the
+// configured CA when ADMIN_TLS_CLIENT_CA_FILE is set, which validation
+// enforces whenever ADMIN_HOST is.
+func NewAdminTLSConfig(conf *config.Config, base *tls.Config) (*tls.Config, error) {
+	if base == nil {
+		return nil, nil
+	}
+
+	tlsConfig := base.Clone()
+	tlsConfig.NextProtos = nextProtos(conf)
+	if conf.AdminTLSClientCAFile == "" {
+		return tlsConfig, nil
+	}
+
+	caPEM, err := os.ReadFile(conf.AdminTLSClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read admin client CA file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("no certificates found in admin client CA file %v", conf.AdminTLSClientCAFile)
+	}
+	tlsConfig.ClientCAs = pool
+	tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	return tlsConfig, nil
+}
+
+func nextProtos(conf *config.Config) []string {
+	if conf.ServerHTTP2 {
+		return []string{"h2", "http/1.1"}
+	}
+	return []string{"http/1.1"}
+}
+
+// redirectToHTTPS sends every request to the same host and path on the HTTPS
+// listener bound to httpsAddr.
+func redirectToHTTPS(httpsAddr string) http.Handler {
+	_, port, _ := net.SplitHostPort(httpsAddr)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if port != "" && port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/sunba23/news/config"
+)
+
+// testCert is a certificate with its key, written as PEM files.
+type testCert struct {
+	cert     *x509.Certificate
+	key      *ecdsa.PrivateKey
+	certFile string
+	keyFile  string
+}
+
+// newTestCert creates a certificate for name signed by parent, or
+// self-signed when parent is nil, and writes it to dir.
+func newTestCert(t *testing.T, dir, name string, isCA bool, parent *testCert) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: name},
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+	}
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tc := &testCert{
+		cert:     cert,
+		key:      key,
+		certFile: filepath.Join(dir, name+".crt"),
+		keyFile:  filepath.Join(dir, name+".key"),
+	}
+	writePEM(t, tc.certFile, "CERTIFICATE", der)
+	writePEM(t, tc.keyFile, "EC PRIVATE KEY", keyDER)
+	return tc
+}
+
+func writePEM(t *testing.T, file, blockType string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (tc *testCert) tlsCertificate(t *testing.T) tls.Certificate {
+	t.Helper()
+	cert, err := tls.LoadX509KeyPair(tc.certFile, tc.keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func TestCertReloaderReloadsOnSIGHUP(t *testing.T) {
+	dir := t.TempDir()
+	first := newTestCert(t, dir, "server", false, nil)
+
+	reloader, err := newCertReloader(first.certFile, first.keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	served, err := reloader.GetCertificate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(served.Certificate[0], first.cert.Raw) {
+		t.Fatal("GetCertificate() does not serve the certificate on disk")
+	}
+
+	// overwrite the files, as a renewal would
+	second := newTestCert(t, dir, "server", false, nil)
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		served, err := reloader.GetCertificate(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Equal(served.Certificate[0], second.cert.Raw) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the certificate was not reloaded after SIGHUP")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRedirectToHTTPS(t *testing.T) {
+	tests := []struct {
+		httpsAddr string
+		target    string
+		want      string
+	}{
+		{":8443", "http://example.com:8080/news?tag=go&limit=5", "https://example.com:8443/news?tag=go&limit=5"},
+		{"0.0.0.0:443", "http://example.com:8080/tags/k8s/news?descendants=true", "https://example.com/tags/k8s/news?descendants=true"},
+		{":8443", "http://example.com/", "https://example.com:8443/"},
+		{":8443", "http://[::1]:8080/news/1", "https://[::1]:8443/news/1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			redirectToHTTPS(tt.httpsAddr).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			if rec.Code != http.StatusMovedPermanently {
+				t.Errorf("status = %v, want %v", rec.Code, http.StatusMovedPermanently)
+			}
+			if got := rec.Header().Get("Location"); got != tt.want {
+				t.Errorf("Location = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdminTLSRequiresClientCertificate(t *testing.T) {
+	dir := t.TempDir()
+	server := newTestCert(t, dir, "server", false, nil)
+	ca := newTestCert(t, dir, "admin-ca", true, nil)
+	admin := newTestCert(t, dir, "admin", false, ca)
+	stranger := newTestCert(t, dir, "stranger", false, nil)
+
+	conf := &config.Config{
+		TLSCertFile:          server.certFile,
+		TLSKeyFile:           server.keyFile,
+		AdminTLSClientCAFile: ca.certFile,
+		ServerHTTP2:          true,
+	}
+	base, _, err := NewTLSConfig(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	adminConfig, err := NewAdminTLSConfig(conf, base)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", adminConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	handshakes := make(chan error)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			err = conn.(*tls.Conn).Handshake()
+			conn.Close()
+			handshakes <- err
+		}
+	}()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.cert)
+	tests := []struct {
+		name    string
+		certs   []tls.Certificate
+		succeed bool
+	}{
+		{"without a client certificate", nil, false},
+		{"with a certificate from another CA", []tls.Certificate{stranger.tlsCertificate(t)}, false},
+		{"with a certificate from the admin CA", []tls.Certificate{admin.tlsCertificate(t)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, err := tls.Dial("tcp", listener.Addr().String(), &tls.Config{
+				RootCAs:      roots,
+				ServerName:   "localhost",
+				Certificates: tt.certs,
+			})
+			if err == nil {
+				// with TLS 1.3 the server checks the client certificate
+				// after the client considers the handshake done
+				conn.Read(make([]byte, 1))
+				conn.Close()
+			}
+			serverErr := <-handshakes
+			if tt.succeed && serverErr != nil {
+				t.Errorf("handshake failed: %v", serverErr)
+			}
+			if !tt.succeed && serverErr == nil {
+				t.Error("handshake succeeded, want it refused")
+			}
+		})
+	}
+}
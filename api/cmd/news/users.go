@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sunba23/news/internal/audit"
+	"github.com/sunba23/news/internal/database"
+	"github.com/sunba23/news/internal/news"
+)
+
+// RunUsers runs the user administration subcommands.
+func RunUsers(ctx context.Context, app *news.Application, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing users subcommand, expected purge")
+	}
+	switch subcommand := args[0]; subcommand {
+	case "purge":
+		return runUsersPurge(ctx, app, args[1:])
+	default:
+		return fmt.Errorf("unknown users subcommand %q, expected purge", subcommand)
+	}
+}
+
+// runUsersPurge deletes the users whose deletion grace period is over, or a
+// single user who requested deletion regardless of it.
+func runUsersPurge(ctx context.Context, app *news.Application, args []string) error {
+	flags := flag.NewFlagSet("users purge", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "log the users to purge without deleting them")
+	userID := flags.String("user", "", "purge this user now instead of those past the grace period")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	graceDays := app.Config().UsersDeletionGraceDays
+	return app.RunTask(ctx, func(ctx context.Context) error {
+		repository := *app.Repository()
+		auditor := audit.NewAuditor(repository)
+
+		var users []database.User
+		if *userID != "" {
+			user, err := repository.GetUserByID(ctx, *userID)
+			if err != nil {
+				return err
+			}
+			if user == nil {
+				return fmt.Errorf("user %v not found", *userID)
+			}
+			if user.DeletionRequestedAt == nil {
+				return fmt.Errorf("user %v has not requested deletion", *userID)
+			}
+			users = append(users, *user)
+		} else {
+			var err error
+			users, err = repository.GetUsersPendingDeletion(ctx, time.Now().AddDate(0, 0, -graceDays))
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, user := range users {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			log.Info().Str("user_id", user.ID).Time("deletion_requested_at", *user.DeletionRequestedAt).Bool("dry_run", *dryRun).Msg("Purging user")
+			if !*dryRun {
+				if err := repository.PurgeUser(ctx, user.ID); err != nil {
+					return fmt.Errorf("purging user %v failed: %w", user.ID, err)
+				}
+				auditor.Add(ctx, database.AuditEvent{
+					ActorID: audit.ActorSystem, Action: audit.ActionPurge, TargetType: audit.TargetUser, TargetID: user.ID,
+				})
+			}
+		}
+		log.Info().Int("users", len(users)).Bool("dry_run", *dryRun).Msg("Purge complete")
+		return nil
+	})
+}
@@ -2,10 +2,13 @@ package config
 
 import (
 	"fmt"
+	"maps"
+	"os"
 	"reflect"
+	"strconv"
 	"strings"
+	"time"
 
-	"github.com/go-playground/validator/v10"
 	"github.com/joho/godotenv"
 	"github.com/mitchellh/mapstructure"
 	"github.com/rs/zerolog/log"
@@ -13,40 +16,150 @@ import (
 )
 
 type Config struct {
-	ServerHost                string `mapstructure:"SERVER_HOST"`
-	ServerReadTimeoutSeconds  int    `mapstructure:"SERVER_READ_TIMEOUT"`
-	ServerShutdownWaitSeconds int    `mapstructure:"SERVER_SHUTDOWN_WAIT"`
+	ServerHost         string        `mapstructure:"SERVER_HOST" file:"server.host" validate:"listen_addr"`
+	ServerReadTimeout  time.Duration `mapstructure:"SERVER_READ_TIMEOUT" file:"server.read_timeout" validate:"gt=0"`
+	ServerShutdownWait time.Duration `mapstructure:"SERVER_SHUTDOWN_WAIT" file:"server.shutdown_wait" validate:"gte=0"`
+	ServerDrainDelay   time.Duration `mapstructure:"SERVER_DRAIN_DELAY" file:"server.drain_delay" reload:"true" validate:"gte=0"`
+	ServerHTTP2        bool          `mapstructure:"SERVER_HTTP2" file:"server.http2"`
 
-	LoggingPretty bool   `mapstructure:"LOGGING_PRETTY"`
-	LoggingLevel  string `mapstructure:"LOGGING_LEVEL"`
+	TLSCertFile     string   `mapstructure:"TLS_CERT_FILE" file:"tls.cert_file" validate:"required_with=TLSKeyFile,excluded_with=TLSAcmeEnabled,omitempty,file"`
+	TLSKeyFile      string   `mapstructure:"TLS_KEY_FILE" file:"tls.key_file" validate:"required_with=TLSCertFile,omitempty,file"`
+	TLSAcmeEnabled  bool     `mapstructure:"TLS_ACME_ENABLED" file:"tls.acme.enabled"`
+	TLSAcmeDomains  []string `mapstructure:"TLS_ACME_DOMAINS" file:"tls.acme.domains" validate:"required_if=TLSAcmeEnabled true,dive,hostname"`
+	TLSAcmeEmail    string   `mapstructure:"TLS_ACME_EMAIL" file:"tls.acme.email" validate:"omitempty,email"`
+	TLSAcmeCacheDir string   `mapstructure:"TLS_ACME_CACHE_DIR" file:"tls.acme.cache_dir"`
+	TLSRedirectHost string   `mapstructure:"TLS_REDIRECT_HOST" file:"tls.redirect_host" validate:"omitempty,listen_addr"`
 
-	PostgresConnStr string `mapstructure:"POSTGRES_CONN_STR" validate:"required"`
+	AdminHost            string `mapstructure:"ADMIN_HOST" file:"admin.host" validate:"omitempty,listen_addr"`
+	AdminTLSClientCAFile string `mapstructure:"ADMIN_TLS_CLIENT_CA_FILE" file:"admin.tls_client_ca_file" validate:"omitempty,file"`
 
-	GoogleOauthRedirectUrl  string   `mapstructure:"GOOGLE_OAUTH_REDIRECT_URL"`
-	GoogleOauthClientId     string   `mapstructure:"GOOGLE_OAUTH_CLIENT_ID" validate:"required"`
-	GoogleOauthClientSecret string   `mapstructure:"GOOGLE_OAUTH_CLIENT_SECRET" validate:"required"`
-	GoogleOauthScopes       []string `mapstructure:"GOOGLE_OAUTH_SCOPES"`
+	CompressionMinSize int `mapstructure:"COMPRESSION_MIN_SIZE" file:"server.compression_min_size" reload:"true" validate:"gte=0"`
 
-	SessionSecret string `mapstructure:"SESSION_SECRET" validate:"required"`
+	StoriesClusterInterval time.Duration `mapstructure:"STORIES_CLUSTER_INTERVAL" file:"stories.cluster_interval" validate:"gt=0"`
+	StoriesMaxDistance     int           `mapstructure:"STORIES_MAX_DISTANCE" file:"stories.max_distance" validate:"gte=0,lte=64"`
+
+	TrendsRefreshInterval time.Duration `mapstructure:"TRENDS_REFRESH_INTERVAL" file:"trends.refresh_interval" validate:"gt=0"`
+
+	SearchesMatchInterval time.Duration `mapstructure:"SEARCHES_MATCH_INTERVAL" file:"searches.match_interval" validate:"gt=0"`
+
+	UsersDeletionGraceDays int `mapstructure:"USERS_DELETION_GRACE_DAYS" file:"users.deletion_grace_days" reload:"true" validate:"gte=0"`
+
+	TaggingRulesFile     string  `mapstructure:"TAGGING_RULES_FILE" file:"tagging.rules_file" validate:"omitempty,file"`
+	TaggingMinConfidence float64 `mapstructure:"TAGGING_MIN_CONFIDENCE" file:"tagging.min_confidence" validate:"gte=0,lte=1"`
+
+	ErrorReportingDSN string `mapstructure:"ERROR_REPORTING_DSN" file:"logging.error_reporting_dsn" secret:"url" validate:"omitempty,url"`
+
+	LoggingPretty bool   `mapstructure:"LOGGING_PRETTY" file:"logging.pretty"`
+	LoggingLevel  string `mapstructure:"LOGGING_LEVEL" file:"logging.level" reload:"true" validate:"oneof=debug info warn error"`
+
+	Storage         string `mapstructure:"STORAGE" file:"db.storage" validate:"oneof=sql postgres memory"`
+	DatabaseURL     string `mapstructure:"DATABASE_URL" file:"db.url" secret:"url" validate:"omitempty,database_url"`
+	PostgresConnStr string `mapstructure:"POSTGRES_CONN_STR" file:"db.postgres_conn_str" secret:"url"`
+
+	GoogleOauthRedirectUrl  string   `mapstructure:"GOOGLE_OAUTH_REDIRECT_URL" file:"auth.google.redirect_url" validate:"required,url"`
+	GoogleOauthClientId     string   `mapstructure:"GOOGLE_OAUTH_CLIENT_ID" file:"auth.google.client_id" validate:"required"`
+	GoogleOauthClientSecret string   `mapstructure:"GOOGLE_OAUTH_CLIENT_SECRET" file:"auth.google.client_secret" secret:"true" validate:"required"`
+	GoogleOauthScopes       []string `mapstructure:"GOOGLE_OAUTH_SCOPES" file:"auth.google.scopes"`
+
+	SessionSecret string `mapstructure:"SESSION_SECRET" file:"auth.session_secret" secret:"true" validate:"required"`
+
+	// sources records where each setting, by its environment variable name,
+	// was taken from
+	sources map[string]Source
 }
 
+// Source is the layer a setting was taken from.
+type Source string
+
+const (
+	SourceDefault Source = "default"
+	SourceFile    Source = "file"
+	SourceEnv     Source = "env"
+	SourceFlag    Source = "flag"
+)
+
+var (
+	configFile string
+	overrides  = map[string]any{}
+)
+
+// InitConfig loads the .env files into the environment and reads the
+// configuration with NewConfig.
 func InitConfig(dotEnvFilenames ...string) (*Config, error) {
+	LoadDotEnv(dotEnvFilenames...)
+	return NewConfig()
+}
+
+// LoadDotEnv sets environment variables from .env files, keeping the ones
+// already set.
+func LoadDotEnv(dotEnvFilenames ...string) {
 	if err := godotenv.Load(dotEnvFilenames...); err != nil {
 		log.Error().Err(err).Send()
 	}
+}
 
-	return NewConfig()
+// SetConfigFile sets the YAML or TOML file to read settings from. It must be
+// called before NewConfig.
+func SetConfigFile(path string) {
+	configFile = path
+}
+
+// ConfigFile returns the file set with SetConfigFile, if any.
+func ConfigFile() string {
+	return configFile
 }
 
+// Override sets a configuration value with precedence over the config file
+// and the environment, e.g. from a command line flag. The key is either the
+// environment variable name or the path in the config file, e.g. SERVER_HOST
+// or server.host. It must be called before NewConfig.
+func Override(key string, value any) error {
+	f, ok := lookupField(key)
+	if !ok {
+		return fmt.Errorf("unknown config setting %q", key)
+	}
+	overrides[f.env] = value
+	return nil
+}
+
+// NewConfig reads and validates the configuration. Settings are layered,
+// each layer taking precedence over the previous one: defaults, the config
+// file, environment variables and overrides.
 func NewConfig() (*Config, error) {
-	cfg := &Config{}
+	cfg, err := LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+	if err = cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// LoadConfig reads the configuration like NewConfig without validating it.
+func LoadConfig() (*Config, error) {
+	file, err := readConfigFile(configFile)
+	if err != nil {
+		return nil, err
+	}
 
-	setDefaults()
-	bindEnvVars(cfg)
-	err := viper.Unmarshal(
+	v := viper.New()
+	setDefaults(v)
+	// viper lowercases the keys of the map it merges
+	if err = v.MergeConfigMap(maps.Clone(file)); err != nil {
+		return nil, fmt.Errorf("unable to merge the config file: %v", err)
+	}
+	bindEnvVars(v)
+	for key, value := range overrides {
+		v.Set(key, value)
+	}
+
+	cfg := &Config{}
+	err = v.Unmarshal(
 		cfg,
 		viper.DecodeHook(
 			mapstructure.ComposeDecodeHookFunc(
+				secondsToDurationHookFunc,
 				mapstructure.StringToTimeDurationHookFunc(),
 				mapstructure.StringToSliceHookFunc(","),
 				mapstructure.TextUnmarshallerHookFunc(),
@@ -56,18 +169,31 @@ func NewConfig() (*Config, error) {
 	if err != nil {
 		return nil, fmt.Errorf("unable to unmarshall the config: %v", err)
 	}
-
-	if err = validateConfig(cfg); err != nil {
-		return nil, err
+	cfg.LoggingLevel = strings.ToLower(cfg.LoggingLevel)
+	// postgres was the name of the sql storage before SQLite was supported
+	if cfg.Storage == "postgres" {
+		cfg.Storage = "sql"
 	}
+	cfg.sources = sourcesOf(file)
 	return cfg, nil
 }
 
-func setDefaults() {
+func setDefaults(v *viper.Viper) {
 	var defaults = map[string]any{
 		"SERVER_HOST":               "0.0.0.0:8000",
-		"SERVER_READ_TIMEOUT":       15,
-		"SERVER_SHUTDOWN_WAIT":      3,
+		"SERVER_READ_TIMEOUT":       15 * time.Second,
+		"SERVER_SHUTDOWN_WAIT":      3 * time.Second,
+		"SERVER_DRAIN_DELAY":        5 * time.Second,
+		"SERVER_HTTP2":              true,
+		"TLS_ACME_CACHE_DIR":        "certs-cache",
+		"STORAGE":                   "sql",
+		"COMPRESSION_MIN_SIZE":      1024,
+		"STORIES_CLUSTER_INTERVAL":  time.Minute,
+		"STORIES_MAX_DISTANCE":      6,
+		"TRENDS_REFRESH_INTERVAL":   5 * time.Minute,
+		"SEARCHES_MATCH_INTERVAL":   time.Minute,
+		"USERS_DELETION_GRACE_DAYS": 30,
+		"TAGGING_MIN_CONFIDENCE":    0.5,
 		"LOGGING_PRETTY":            true,
 		"LOGGING_LEVEL":             "debug",
 		"GOOGLE_OAUTH_REDIRECT_URL": "http://localhost:8000/auth/google/callback",
@@ -75,37 +201,79 @@ func setDefaults() {
 	}
 
 	for key, value := range defaults {
-		if val, ok := value.(map[string]any); ok {
-			for subKey, subValue := range val {
-				viper.SetDefault(fmt.Sprintf("%v.%v", key, subKey), subValue)
-			}
-		} else {
-			viper.SetDefault(key, value)
-		}
+		v.SetDefault(key, value)
 	}
 }
 
-func bindEnvVars(cfg *Config) {
-	viper.AutomaticEnv()
-	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+func bindEnvVars(v *viper.Viper) {
+	v.AutomaticEnv()
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 
-	cfgType := reflect.TypeOf(cfg).Elem()
-	for i := range cfgType.NumField() {
-		field := cfgType.Field(i)
-		tag := field.Tag.Get("mapstructure")
-		if tag == "" {
-			continue
+	for _, f := range fields {
+		if err := v.BindEnv(f.env); err != nil {
+			log.Fatal().Str("env_var", f.env).Err(err).Msg("Failed to bind env var")
 		}
-		if err := viper.BindEnv(tag); err != nil {
-			log.Fatal().Str("env_var", tag).Err(err).Msg("Failed to bind env var")
+	}
+}
+
+// sourcesOf tells for every setting which layer its value comes from, given
+// the settings read from the config file.
+func sourcesOf(file map[string]any) map[string]Source {
+	sources := make(map[string]Source, len(fields))
+	for _, f := range fields {
+		_, overridden := overrides[f.env]
+		_, inFile := file[f.env]
+		switch {
+		case overridden:
+			sources[f.env] = SourceFlag
+		case os.Getenv(f.env) != "":
+			sources[f.env] = SourceEnv
+		case inFile:
+			sources[f.env] = SourceFile
+		default:
+			sources[f.env] = SourceDefault
 		}
 	}
+	return sources
 }
 
-func validateConfig(cfg *Config) error {
-	validate := validator.New()
-	if err := validate.Struct(cfg); err != nil {
-		return fmt.Errorf("config validation errors:\n%v", err)
+// secondsToDurationHookFunc decodes plain numbers into durations as seconds,
+// so that SERVER_READ_TIMEOUT=15 keeps working next to values such as 15s.
+func secondsToDurationHookFunc(from reflect.Type, to reflect.Type, data any) (any, error) {
+	if to != reflect.TypeOf(time.Duration(0)) || from == to {
+		return data, nil
 	}
-	return nil
+	var seconds float64
+	switch from.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		seconds = float64(reflect.ValueOf(data).Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		seconds = float64(reflect.ValueOf(data).Uint())
+	case reflect.Float32, reflect.Float64:
+		seconds = reflect.ValueOf(data).Float()
+	case reflect.String:
+		n, err := strconv.ParseFloat(strings.TrimSpace(data.(string)), 64)
+		if err != nil {
+			return data, nil
+		}
+		seconds = n
+	default:
+		return data, nil
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// DatabaseDSN returns the database to connect to. DATABASE_URL takes
+// precedence over the older POSTGRES_CONN_STR.
+func (cfg *Config) DatabaseDSN() string {
+	if cfg.DatabaseURL != "" {
+		return cfg.DatabaseURL
+	}
+	return cfg.PostgresConnStr
+}
+
+// TLSEnabled reports whether the server should terminate TLS itself, either
+// with static certificate files or with certificates obtained through ACME.
+func (cfg *Config) TLSEnabled() bool {
+	return cfg.TLSCertFile != "" || cfg.TLSAcmeEnabled
 }
@@ -0,0 +1,251 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// useConfig makes LoadConfig read file, with content, and the given
+// overrides, restoring the package state after the test.
+func useConfig(t *testing.T, name, content string, flags map[string]any) {
+	t.Helper()
+	path := ""
+	if name != "" {
+		path = filepath.Join(t.TempDir(), name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	SetConfigFile(path)
+	for key, value := range flags {
+		if err := Override(key, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Cleanup(func() {
+		configFile = ""
+		overrides = map[string]any{}
+	})
+}
+
+func setting(t *testing.T, cfg *Config, key string) Setting {
+	t.Helper()
+	for _, s := range cfg.Settings(false) {
+		if s.Key == key {
+			return s
+		}
+	}
+	t.Fatalf("no setting %v", key)
+	return Setting{}
+}
+
+func TestLoadConfigLayers(t *testing.T) {
+	useConfig(t, "config.yaml", `
+server:
+  host: 127.0.0.1:1000
+  read_timeout: 20
+  compression_min_size: 2048
+stories:
+  max_distance: 8
+logging:
+  level: info
+`, map[string]any{"server.host": "127.0.0.1:3000"})
+	t.Setenv("SERVER_HOST", "127.0.0.1:2000")
+	t.Setenv("STORIES_MAX_DISTANCE", "10")
+	t.Setenv("LOGGING_LEVEL", "WARN")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		key    string
+		value  any
+		source Source
+	}{
+		// flags > env > file > defaults
+		{"SERVER_HOST", "127.0.0.1:3000", SourceFlag},
+		{"STORIES_MAX_DISTANCE", 10, SourceEnv},
+		{"LOGGING_LEVEL", "warn", SourceEnv},
+		{"COMPRESSION_MIN_SIZE", 2048, SourceFile},
+		{"SERVER_READ_TIMEOUT", 20 * time.Second, SourceFile},
+		{"SERVER_SHUTDOWN_WAIT", 3 * time.Second, SourceDefault},
+		{"GOOGLE_OAUTH_SCOPES", []string{"https://www.googleapis.com/auth/userinfo.email"}, SourceDefault},
+	}
+	for _, tt := range tests {
+		s := setting(t, cfg, tt.key)
+		if !reflect.DeepEqual(s.Value, tt.value) || s.Source != tt.source {
+			t.Errorf("%v = %v from %v, want %v from %v", tt.key, s.Value, s.Source, tt.value, tt.source)
+		}
+	}
+}
+
+func TestLoadConfigFileFormats(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"config.yaml", `
+server:
+  drain_delay: 1m
+  http2: false
+tls:
+  acme:
+    domains: [news.example.com, www.example.com]
+tagging:
+  min_confidence: 0.75
+`},
+		{"config.toml", `
+[server]
+drain_delay = "1m"
+http2 = false
+
+[tls.acme]
+domains = ["news.example.com", "www.example.com"]
+
+[tagging]
+min_confidence = 0.75
+`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useConfig(t, tt.name, tt.content, nil)
+			cfg, err := LoadConfig()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.ServerDrainDelay != time.Minute || cfg.ServerHTTP2 || cfg.TaggingMinConfidence != 0.75 {
+				t.Errorf("got drain delay %v, http2 %v and min confidence %v, want 1m, false and 0.75",
+					cfg.ServerDrainDelay, cfg.ServerHTTP2, cfg.TaggingMinConfidence)
+			}
+			if want := []string{"news.example.com", "www.example.com"}; !slices.Equal(cfg.TLSAcmeDomains, want) {
+				t.Errorf("TLS_ACME_DOMAINS = %v, want %v", cfg.TLSAcmeDomains, want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigRejectsUnknownSettings(t *testing.T) {
+	// settings are only known by their path in the file, not by their
+	// environment variable name
+	useConfig(t, "config.yaml", `
+server:
+  hots: 127.0.0.1:1000
+SERVER_HOST: 127.0.0.1:1000
+colour: blue
+`, nil)
+	_, err := LoadConfig()
+	if err == nil || !strings.Contains(err.Error(), "colour, server.hots, server_host") {
+		t.Errorf("LoadConfig() error = %v, want the unknown settings listed", err)
+	}
+}
+
+func TestLoadConfigEnvValues(t *testing.T) {
+	useConfig(t, "", "", nil)
+	t.Setenv("SERVER_READ_TIMEOUT", "1.5")
+	t.Setenv("SERVER_SHUTDOWN_WAIT", "2m")
+	t.Setenv("TLS_ACME_DOMAINS", "a.example.com,b.example.com")
+	t.Setenv("STORAGE", "postgres")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ServerReadTimeout != 1500*time.Millisecond || cfg.ServerShutdownWait != 2*time.Minute {
+		t.Errorf("durations = %v and %v, want 1.5s and 2m", cfg.ServerReadTimeout, cfg.ServerShutdownWait)
+	}
+	if want := []string{"a.example.com", "b.example.com"}; !slices.Equal(cfg.TLSAcmeDomains, want) {
+		t.Errorf("TLS_ACME_DOMAINS = %v, want %v", cfg.TLSAcmeDomains, want)
+	}
+	if cfg.Storage != "sql" {
+		t.Errorf("STORAGE = %v, want postgres to mean sql", cfg.Storage)
+	}
+}
+
+func TestOverrideUnknownSetting(t *testing.T) {
+	t.Cleanup(func() { overrides = map[string]any{} })
+	if err := Override("server.hots", "x"); err == nil {
+		t.Error("Override() accepted an unknown setting")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.crt")
+	if err := os.WriteFile(caFile, []byte("not checked here"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	valid := func() *Config {
+		return &Config{
+			ServerHost:              "0.0.0.0:8000",
+			ServerReadTimeout:       time.Second,
+			StoriesClusterInterval:  time.Minute,
+			TrendsRefreshInterval:   time.Minute,
+			SearchesMatchInterval:   time.Minute,
+			LoggingLevel:            "info",
+			Storage:                 "memory",
+			GoogleOauthRedirectUrl:  "http://localhost:8000/auth/google/callback",
+			GoogleOauthClientId:     "id",
+			GoogleOauthClientSecret: "secret",
+			SessionSecret:           "secret",
+		}
+	}
+	tests := []struct {
+		name   string
+		change func(*Config)
+		want   []string
+	}{
+		{"valid", func(*Config) {}, nil},
+		{"required and invalid values", func(cfg *Config) {
+			cfg.SessionSecret = ""
+			cfg.ServerHost = "localhost"
+			cfg.GoogleOauthClientSecret = ""
+		}, []string{
+			"SERVER_HOST (server.host)",
+			"GOOGLE_OAUTH_CLIENT_SECRET (auth.google.client_secret) is required",
+			"SESSION_SECRET (auth.session_secret) is required",
+		}},
+		{"sql storage without a database", func(cfg *Config) { cfg.Storage = "sql" }, []string{
+			"DATABASE_URL (db.url) or POSTGRES_CONN_STR (db.postgres_conn_str) is required with STORAGE (db.storage) sql",
+		}},
+		{"admin listener without TLS", func(cfg *Config) { cfg.AdminHost = "127.0.0.1:9000" }, []string{
+			"ADMIN_HOST (admin.host) requires TLS and ADMIN_TLS_CLIENT_CA_FILE (admin.tls_client_ca_file) to be configured",
+		}},
+		{"admin listener with mutual TLS", func(cfg *Config) {
+			cfg.AdminHost = "127.0.0.1:9000"
+			cfg.AdminTLSClientCAFile = caFile
+			cfg.TLSAcmeEnabled = true
+			cfg.TLSAcmeDomains = []string{"news.example.com"}
+		}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid()
+			tt.change(cfg)
+			err := cfg.Validate()
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) || len(validationErr.Problems) != len(tt.want) {
+				t.Fatalf("Validate() = %v, want %v problems", err, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !strings.HasPrefix(validationErr.Problems[i], want) {
+					t.Errorf("problem %v = %q, want %q", i, validationErr.Problems[i], want)
+				}
+			}
+		})
+	}
+}
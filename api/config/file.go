@@ -0,0 +1,101 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// field describes a setting of Config, named by its environment variable
+// and by its path in the config file.
+type field struct {
+	name   string
+	env    string
+	path   string
+	secret string
+	// reload tells whether the setting can change while the server runs
+	reload bool
+}
+
+func (f field) String() string {
+	return fmt.Sprintf("%s (%s)", f.env, f.path)
+}
+
+var fields = configFields()
+
+func configFields() []field {
+	var fields []field
+	cfgType := reflect.TypeOf(Config{})
+	for i := range cfgType.NumField() {
+		structField := cfgType.Field(i)
+		env := structField.Tag.Get("mapstructure")
+		if env == "" {
+			continue
+		}
+		fields = append(fields, field{
+			name:   structField.Name,
+			env:    env,
+			path:   structField.Tag.Get("file"),
+			secret: structField.Tag.Get("secret"),
+			reload: structField.Tag.Get("reload") == "true",
+		})
+	}
+	return fields
+}
+
+// lookupField finds a setting by its environment variable name or its path
+// in the config file.
+func lookupField(key string) (field, bool) {
+	for _, f := range fields {
+		if strings.EqualFold(key, f.env) || strings.EqualFold(key, f.path) {
+			return f, true
+		}
+	}
+	return field{}, false
+}
+
+func fieldByName(name string) (field, bool) {
+	for _, f := range fields {
+		if f.name == name {
+			return f, true
+		}
+	}
+	return field{}, false
+}
+
+// readConfigFile reads a YAML or TOML config file with settings nested in
+// sections, e.g. host in server for SERVER_HOST. The settings are returned
+// by environment variable name; unknown ones are rejected so that typos
+// don't go unnoticed.
+func readConfigFile(path string) (map[string]any, error) {
+	settings := map[string]any{}
+	if path == "" {
+		return settings, nil
+	}
+
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("unable to read the config file: %v", err)
+	}
+
+	var unknown []string
+	for _, key := range v.AllKeys() {
+		f, ok := lookupField(key)
+		if !ok || !strings.EqualFold(key, f.path) {
+			unknown = append(unknown, key)
+			continue
+		}
+		if value := v.Get(key); value != nil {
+			settings[f.env] = value
+		}
+	}
+	if len(unknown) > 0 {
+		slices.Sort(unknown)
+		return nil, fmt.Errorf("unknown settings in the config file %s: %s", path, strings.Join(unknown, ", "))
+	}
+	return settings, nil
+}
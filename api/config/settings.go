@@ -0,0 +1,104 @@
+package config
+
+import (
+	"net/url"
+	"reflect"
+)
+
+const redacted = "xxxxx"
+
+// Setting is the effective value of a configuration setting.
+type Setting struct {
+	// Key is the environment variable name.
+	Key string
+	// Path is the dotted path in the config file.
+	Path   string
+	Value  any
+	Source Source
+	Secret bool
+}
+
+// Settings lists the effective configuration in declaration order. With
+// redact, secrets are masked: entirely, or only the credentials of URLs.
+func (cfg *Config) Settings(redact bool) []Setting {
+	value := reflect.ValueOf(cfg).Elem()
+	settings := make([]Setting, 0, len(fields))
+	for _, f := range fields {
+		setting := Setting{
+			Key:    f.env,
+			Path:   f.path,
+			Value:  value.FieldByName(f.name).Interface(),
+			Source: cfg.sources[f.env],
+			Secret: f.secret != "",
+		}
+		if redact && setting.Secret {
+			setting.Value = redactValue(f.secret, setting.Value.(string))
+		}
+		settings = append(settings, setting)
+	}
+	return settings
+}
+
+func redactValue(kind string, value string) string {
+	if value == "" {
+		return ""
+	}
+	if kind != "url" {
+		return redacted
+	}
+
+	// connection strings that are not URLs, e.g. "host=db password=...",
+	// are masked entirely
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme == "" || u.Opaque != "" {
+		return redacted
+	}
+	if u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), redacted)
+		} else {
+			// e.g. the public key of an error reporting DSN
+			u.User = url.User(redacted)
+		}
+	}
+	if query := u.Query(); query.Has("password") {
+		query.Set("password", redacted)
+		u.RawQuery = query.Encode()
+	}
+	return u.String()
+}
+
+// Change is a setting whose value differs between two configurations.
+type Change struct {
+	Key  string
+	Path string
+	// Old and New are the values, with secrets masked.
+	Old any
+	New any
+	// Reloadable tells whether the change can be applied without a restart.
+	Reloadable bool
+}
+
+// Changes lists the settings whose value differs in next, in declaration
+// order.
+func (cfg *Config) Changes(next *Config) []Change {
+	current, updated := reflect.ValueOf(cfg).Elem(), reflect.ValueOf(next).Elem()
+	var changes []Change
+	for _, f := range fields {
+		before, after := current.FieldByName(f.name).Interface(), updated.FieldByName(f.name).Interface()
+		if reflect.DeepEqual(before, after) {
+			continue
+		}
+		if f.secret != "" {
+			before, after = redactValue(f.secret, before.(string)), redactValue(f.secret, after.(string))
+		}
+		changes = append(changes, Change{
+			Key:        f.env,
+			Path:       f.path,
+			Old:        before,
+			New:        after,
+			Reloadable: f.reload,
+		})
+	}
+	return changes
+}
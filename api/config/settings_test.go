@@ -0,0 +1,65 @@
+package config
+
+import "testing"
+
+func TestRedactValue(t *testing.T) {
+	tests := []struct {
+		kind  string
+		value string
+		want  string
+	}{
+		{"true", "", ""},
+		{"true", "session-secret", "xxxxx"},
+		// secrets that are not URLs are masked entirely
+		{"true", "postgres://news:password@db/news", "xxxxx"},
+		{"url", "postgres://news:password@db:5432/news?sslmode=disable", "postgres://news:xxxxx@db:5432/news?sslmode=disable"},
+		{"url", "postgres://db/news?password=password&sslmode=disable", "postgres://db/news?password=xxxxx&sslmode=disable"},
+		{"url", "https://publickey@errors.example.com/42", "https://xxxxx@errors.example.com/42"},
+		{"url", "postgres://db/news", "postgres://db/news"},
+		{"url", "sqlite:///var/lib/news.db", "sqlite:///var/lib/news.db"},
+		{"url", "host=db user=news password=password", "xxxxx"},
+		{"url", "sqlite:news.db", "xxxxx"},
+	}
+	for _, tt := range tests {
+		if got := redactValue(tt.kind, tt.value); got != tt.want {
+			t.Errorf("redactValue(%q, %q) = %q, want %q", tt.kind, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestSettingsAndChangesMaskSecrets(t *testing.T) {
+	cfg := &Config{SessionSecret: "old-secret", DatabaseURL: "postgres://news:old@db/news", ServerHost: "0.0.0.0:8000"}
+	next := &Config{SessionSecret: "new-secret", DatabaseURL: "postgres://news:new@db/news", ServerHost: "0.0.0.0:8000"}
+
+	values := func(redact bool) map[string]any {
+		values := map[string]any{}
+		for _, s := range cfg.Settings(redact) {
+			values[s.Key] = s.Value
+		}
+		return values
+	}
+	if got := values(true); got["SESSION_SECRET"] != "xxxxx" || got["DATABASE_URL"] != "postgres://news:xxxxx@db/news" {
+		t.Errorf("Settings(redact) = %v and %v, want them masked", got["SESSION_SECRET"], got["DATABASE_URL"])
+	}
+	if got := values(false); got["SESSION_SECRET"] != "old-secret" {
+		t.Errorf("Settings() SESSION_SECRET = %v, want it unmasked", got["SESSION_SECRET"])
+	}
+
+	changes := cfg.Changes(next)
+	if len(changes) != 2 {
+		t.Fatalf("Changes() = %+v, want SESSION_SECRET and DATABASE_URL", changes)
+	}
+	// the changes are reported even though both values look the same masked
+	for _, c := range changes {
+		switch c.Key {
+		case "SESSION_SECRET":
+			if c.Old != "xxxxx" || c.New != "xxxxx" {
+				t.Errorf("change of SESSION_SECRET = %v -> %v, want it masked", c.Old, c.New)
+			}
+		case "DATABASE_URL":
+			if c.Old != "postgres://news:xxxxx@db/news" || c.New != "postgres://news:xxxxx@db/news" {
+				t.Errorf("change of DATABASE_URL = %v -> %v, want it masked", c.Old, c.New)
+			}
+		}
+	}
+}
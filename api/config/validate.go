@@ -0,0 +1,133 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// ValidationError lists every problem found in the configuration.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "config validation errors:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	validate := validator.New()
+	validate.RegisterValidation("listen_addr", func(fl validator.FieldLevel) bool {
+		_, port, err := net.SplitHostPort(fl.Field().String())
+		if err != nil {
+			return false
+		}
+		n, err := strconv.Atoi(port)
+		return err == nil && n >= 0 && n <= 65535
+	})
+	validate.RegisterValidation("database_url", func(fl validator.FieldLevel) bool {
+		u, err := url.Parse(fl.Field().String())
+		if err != nil {
+			return false
+		}
+		switch u.Scheme {
+		case "sqlite", "file":
+			return u.Opaque != "" || u.Host != "" || u.Path != ""
+		case "postgres", "postgresql":
+			return true
+		}
+		return false
+	})
+	return validate
+}
+
+// Validate checks the configuration, reporting all problems at once by
+// their environment variable names and config file paths.
+func (cfg *Config) Validate() error {
+	var problems []string
+	if errs, ok := validate.Struct(cfg).(validator.ValidationErrors); ok {
+		for _, fe := range errs {
+			problems = append(problems, describeProblem(fe))
+		}
+	}
+
+	storage, _ := lookupField("STORAGE")
+	databaseURL, _ := lookupField("DATABASE_URL")
+	postgresConnStr, _ := lookupField("POSTGRES_CONN_STR")
+	if cfg.Storage == "sql" && cfg.DatabaseDSN() == "" {
+		problems = append(problems, fmt.Sprintf("%s or %s is required with %s sql", databaseURL, postgresConnStr, storage))
+	}
+	adminHost, _ := lookupField("ADMIN_HOST")
+	adminCA, _ := lookupField("ADMIN_TLS_CLIENT_CA_FILE")
+	if cfg.AdminTLSClientCAFile != "" && !cfg.TLSEnabled() {
+		problems = append(problems, fmt.Sprintf("%s requires TLS to be configured", adminCA))
+	}
+	// the admin listener serves profiles and the audit log, so it is only
+	// started with mutual TLS
+	if cfg.AdminHost != "" && (!cfg.TLSEnabled() || cfg.AdminTLSClientCAFile == "") {
+		problems = append(problems, fmt.Sprintf("%s requires TLS and %s to be configured", adminHost, adminCA))
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+func describeProblem(fe validator.FieldError) string {
+	// elements of lists are reported as e.g. TLSAcmeDomains[0]
+	name, _, _ := strings.Cut(fe.StructField(), "[")
+	f, _ := fieldByName(name)
+	value := fmt.Sprintf("%q", fmt.Sprint(fe.Value()))
+	if f.secret != "" {
+		value = "a value that is not shown"
+	}
+
+	switch fe.Tag() {
+	case "required":
+		return fmt.Sprintf("%s is required", f)
+	case "required_with":
+		return fmt.Sprintf("%s is required with %s", f, paramField(fe.Param()))
+	case "required_if":
+		other, expected, _ := strings.Cut(fe.Param(), " ")
+		return fmt.Sprintf("%s is required when %s is %s", f, paramField(other), expected)
+	case "excluded_with":
+		return fmt.Sprintf("%s can't be set together with %s", f, paramField(fe.Param()))
+	case "gt":
+		return fmt.Sprintf("%s must be greater than %s, got %s", f, fe.Param(), value)
+	case "gte":
+		return fmt.Sprintf("%s must be at least %s, got %s", f, fe.Param(), value)
+	case "lte":
+		return fmt.Sprintf("%s must be at most %s, got %s", f, fe.Param(), value)
+	case "oneof":
+		return fmt.Sprintf("%s must be one of %s, got %s", f, strings.ReplaceAll(fe.Param(), " ", ", "), value)
+	case "url":
+		return fmt.Sprintf("%s must be an absolute URL, got %s", f, value)
+	case "database_url":
+		return fmt.Sprintf("%s must be a sqlite:, file:, postgres:// or postgresql:// URL, got %s", f, value)
+	case "listen_addr":
+		return fmt.Sprintf("%s must be an address such as 0.0.0.0:8000, got %s", f, value)
+	case "hostname":
+		return fmt.Sprintf("%s must only contain host names, got %s", f, value)
+	case "email":
+		return fmt.Sprintf("%s must be an email address, got %s", f, value)
+	case "file":
+		return fmt.Sprintf("%s must be an existing file, got %s", f, value)
+	default:
+		return fmt.Sprintf("%s fails the %s check, got %s", f, fe.Tag(), value)
+	}
+}
+
+// paramField names the setting a validation tag refers to by field name.
+func paramField(name string) string {
+	if f, ok := fieldByName(name); ok {
+		return f.String()
+	}
+	return name
+}
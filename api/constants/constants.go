@@ -3,5 +3,7 @@ package constants
 type ContextKey string
 
 const (
-	UserIdContextKey ContextKey = "userId"
+	UserIdContextKey          ContextKey = "userId"
+	UserPreferencesContextKey ContextKey = "userPreferences"
+	RequestIdContextKey       ContextKey = "requestId"
 )
@@ -0,0 +1,95 @@
+// Package audit records security relevant events and changes made by users
+// and administrators in the append-only audit log.
+package audit
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"github.com/rs/zerolog"
+	"github.com/sunba23/news/constants"
+	"github.com/sunba23/news/internal/database"
+)
+
+// Actions recorded in the audit log.
+const (
+	ActionLogin       = "auth.login"
+	ActionLoginFailed = "auth.login_failed"
+	ActionLogout      = "auth.logout"
+
+	ActionPreferencesUpdate = "user.preferences_update"
+	ActionExport            = "user.export"
+	ActionDeletionRequest   = "user.deletion_request"
+	ActionDeletionCancel    = "user.deletion_cancel"
+	ActionPurge             = "user.purge"
+
+	ActionFavoriteTagAdd    = "favorite_tag.add"
+	ActionFavoriteTagRemove = "favorite_tag.remove"
+	ActionMuteAdd           = "mute.add"
+	ActionMuteRemove        = "mute.remove"
+	ActionBookmarkAdd       = "bookmark.add"
+	ActionBookmarkRemove    = "bookmark.remove"
+	ActionSearchCreate      = "saved_search.create"
+	ActionSearchDelete      = "saved_search.delete"
+
+	ActionTagParentSet   = "tag.parent_set"
+	ActionTagAliasAdd    = "tag_alias.add"
+	ActionTagAliasRemove = "tag_alias.remove"
+
+	// ActionRetag is recorded once per retag run on the news target type,
+	// with the number of news retagged as the target id.
+	ActionRetag = "news.retag"
+)
+
+// Target types of audit events. Mutes use the mute kind.
+const (
+	TargetUser     = "user"
+	TargetTag      = "tag"
+	TargetTagAlias = "tag_alias"
+	TargetNews     = "news"
+	TargetSearch   = "saved_search"
+)
+
+// Actors that are not users.
+const (
+	ActorSystem = "system"
+	ActorAdmin  = "admin"
+)
+
+// Event returns an event of action on the target.
+func Event(action, targetType, targetID string) database.AuditEvent {
+	return database.AuditEvent{Action: action, TargetType: targetType, TargetID: targetID}
+}
+
+type Auditor struct {
+	repository database.Repository
+}
+
+func NewAuditor(repository database.Repository) *Auditor {
+	return &Auditor{repository: repository}
+}
+
+// Add appends the event to the audit log. Failures are logged rather than
+// returned so an unavailable audit log does not fail the audited operation.
+func (a *Auditor) Add(ctx context.Context, event database.AuditEvent) {
+	if err := a.repository.AddAuditEvent(ctx, &event); err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).Str("action", event.Action).Str("actor_id", event.ActorID).Msg("recording audit event failed")
+	}
+}
+
+// Record adds an event caused by the request, filling in the client address,
+// user agent and request id, and the actor from the logged-in user when not
+// set.
+func (a *Auditor) Record(r *http.Request, event database.AuditEvent) {
+	if event.ActorID == "" {
+		event.ActorID, _ = r.Context().Value(constants.UserIdContextKey).(string)
+	}
+	event.IP = r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		event.IP = host
+	}
+	event.UserAgent = r.UserAgent()
+	event.RequestID, _ = r.Context().Value(constants.RequestIdContextKey).(string)
+	a.Add(r.Context(), event)
+}
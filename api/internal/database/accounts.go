@@ -0,0 +1,93 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// RequestUserDeletion schedules the user to be purged and records the
+// request. It does nothing if the user already requested it.
+func (r *SQLRepository) RequestUserDeletion(ctx context.Context, userID string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE users SET deletion_requested_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND deletion_requested_at IS NULL
+	`
+	result, err := tx.ExecContext(ctx, query, userID)
+	if err != nil {
+		return fmt.Errorf("failed to request deletion of user %v: %w", userID, err)
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return err
+	}
+
+	query = `INSERT INTO account_deletions (user_id) VALUES ($1)`
+	if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+		return fmt.Errorf("failed to record deletion of user %v: %w", userID, err)
+	}
+	return tx.Commit()
+}
+
+// CancelUserDeletion keeps a user who requested deletion.
+func (r *SQLRepository) CancelUserDeletion(ctx context.Context, userID string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE users SET deletion_requested_at = NULL WHERE id = $1`
+	if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+		return fmt.Errorf("failed to cancel deletion of user %v: %w", userID, err)
+	}
+	query = `
+		UPDATE account_deletions SET cancelled_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND cancelled_at IS NULL AND purged_at IS NULL
+	`
+	if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+		return fmt.Errorf("failed to record cancelled deletion of user %v: %w", userID, err)
+	}
+	return tx.Commit()
+}
+
+// GetUsersPendingDeletion returns the users who requested deletion before the
+// given time, earliest request first.
+func (r *SQLRepository) GetUsersPendingDeletion(ctx context.Context, requestedBefore time.Time) ([]User, error) {
+	query := `
+		SELECT * FROM users
+		WHERE deletion_requested_at IS NOT NULL AND deletion_requested_at < $1
+		ORDER BY deletion_requested_at, id
+	`
+	var users []User
+	err := r.db.SelectContext(ctx, &users, query, requestedBefore.UTC())
+	return users, err
+}
+
+// PurgeUser deletes the user with all their data, keeping only the record of
+// the deletion.
+func (r *SQLRepository) PurgeUser(ctx context.Context, userID string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `DELETE FROM users WHERE id = $1`
+	if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+		return fmt.Errorf("failed to delete user %v: %w", userID, err)
+	}
+	query = `
+		UPDATE account_deletions SET purged_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND cancelled_at IS NULL AND purged_at IS NULL
+	`
+	if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+		return fmt.Errorf("failed to record purge of user %v: %w", userID, err)
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,107 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// AuditFilter narrows down the audit events returned by GetAuditEvents. Zero
+// values disable the corresponding condition.
+type AuditFilter struct {
+	ActorID string
+	// Action matches exactly, or by prefix when it ends with *, e.g. auth.*.
+	Action     string
+	TargetType string
+	TargetID   string
+	Since      *time.Time
+	Until      *time.Time
+	// BeforeID returns the events older than this one, for paging.
+	BeforeID int64
+	Limit    int
+}
+
+func (f AuditFilter) matches(event AuditEvent) bool {
+	if f.ActorID != "" && event.ActorID != f.ActorID {
+		return false
+	}
+	if prefix, ok := strings.CutSuffix(f.Action, "*"); ok {
+		if !strings.HasPrefix(event.Action, prefix) {
+			return false
+		}
+	} else if f.Action != "" && event.Action != f.Action {
+		return false
+	}
+	if f.TargetType != "" && event.TargetType != f.TargetType {
+		return false
+	}
+	if f.TargetID != "" && event.TargetID != f.TargetID {
+		return false
+	}
+	if f.Since != nil && event.OccurredAt.Before(*f.Since) {
+		return false
+	}
+	if f.Until != nil && !event.OccurredAt.Before(*f.Until) {
+		return false
+	}
+	return f.BeforeID <= 0 || event.ID < f.BeforeID
+}
+
+// AddAuditEvent appends the event to the audit log, filling in its ID and
+// time.
+func (r *SQLRepository) AddAuditEvent(ctx context.Context, event *AuditEvent) error {
+	query := `
+		INSERT INTO audit_events (actor_id, action, target_type, target_id, ip, user_agent, request_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, occurred_at
+	`
+	row := r.db.QueryRowxContext(ctx, query,
+		event.ActorID, event.Action, event.TargetType, event.TargetID, event.IP, event.UserAgent, event.RequestID)
+	if err := row.Scan(&event.ID, &event.OccurredAt); err != nil {
+		return fmt.Errorf("failed to add audit event: %w", err)
+	}
+	return nil
+}
+
+// GetAuditEvents returns the events matching the filter, newest first.
+func (r *SQLRepository) GetAuditEvents(ctx context.Context, filter AuditFilter) ([]AuditEvent, error) {
+	var args queryArgs
+	var conditions []string
+	if filter.ActorID != "" {
+		conditions = append(conditions, "actor_id = "+args.add(filter.ActorID))
+	}
+	if prefix, ok := strings.CutSuffix(filter.Action, "*"); ok {
+		conditions = append(conditions, fmt.Sprintf(`action LIKE %v ESCAPE '\'`, args.add(escapeLike(prefix)+"%")))
+	} else if filter.Action != "" {
+		conditions = append(conditions, "action = "+args.add(filter.Action))
+	}
+	if filter.TargetType != "" {
+		conditions = append(conditions, "target_type = "+args.add(filter.TargetType))
+	}
+	if filter.TargetID != "" {
+		conditions = append(conditions, "target_id = "+args.add(filter.TargetID))
+	}
+	if filter.Since != nil {
+		conditions = append(conditions, "occurred_at >= "+args.add(filter.Since.UTC()))
+	}
+	if filter.Until != nil {
+		conditions = append(conditions, "occurred_at < "+args.add(filter.Until.UTC()))
+	}
+	if filter.BeforeID > 0 {
+		conditions = append(conditions, "id < "+args.add(filter.BeforeID))
+	}
+
+	query := `SELECT * FROM audit_events`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	query += ` ORDER BY id DESC`
+	if filter.Limit > 0 {
+		query += ` LIMIT ` + args.add(filter.Limit)
+	}
+
+	var events []AuditEvent
+	err := r.db.SelectContext(ctx, &events, query, args...)
+	return events, err
+}
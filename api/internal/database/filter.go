@@ -0,0 +1,234 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+type NewsSort string
+
+const (
+	NewsSortNewest    NewsSort = "newest"
+	NewsSortOldest    NewsSort = "oldest"
+	NewsSortRelevance NewsSort = "relevance"
+)
+
+// newsColumns maps the selectable news fields to their columns.
+var newsColumns = map[string]string{
+	"id":         "n.id",
+	"title":      "n.title",
+	"content":    "n.content",
+	"author":     "n.author",
+	"source":     "n.source",
+	"created_at": "n.created_at",
+}
+
+// NewsFieldNames lists the selectable news fields in their canonical order.
+var NewsFieldNames = []string{"id", "title", "content", "author", "source", "created_at"}
+
+// NewsFields selects which columns SearchNews loads. The zero value loads
+// every column and the tags.
+type NewsFields struct {
+	// Columns are names from NewsFieldNames; id is always loaded.
+	Columns []string
+	// OmitTags skips joining news_tags, leaving Tags nil.
+	OmitTags bool
+}
+
+func (f NewsFields) selectList() string {
+	if len(f.Columns) == 0 {
+		return "n.*"
+	}
+	columns := []string{"n.id"}
+	for _, name := range NewsFieldNames {
+		if name != "id" && slices.Contains(f.Columns, name) {
+			columns = append(columns, newsColumns[name])
+		}
+	}
+	return strings.Join(columns, ", ")
+}
+
+// apply clears what the selection does not load, matching what SearchNews
+// returns from SQL.
+func (f NewsFields) apply(n News) News {
+	if f.OmitTags {
+		n.Tags = nil
+	}
+	if len(f.Columns) == 0 {
+		return n
+	}
+	sparse := News{ID: n.ID, Tags: n.Tags}
+	for _, name := range f.Columns {
+		switch name {
+		case "title":
+			sparse.Title = n.Title
+		case "content":
+			sparse.Content = n.Content
+		case "author":
+			sparse.Author = n.Author
+		case "source":
+			sparse.Source = n.Source
+		case "created_at":
+			sparse.CreatedAt = n.CreatedAt
+		}
+	}
+	return sparse
+}
+
+// NewsFilter narrows down and orders the news returned by SearchNews. Zero
+// values disable the corresponding condition.
+type NewsFilter struct {
+	// TagIDs keeps news tagged with any of the tags, or with all of them when
+	// MatchAllTags is set.
+	TagIDs        []int
+	MatchAllTags  bool
+	ExcludeTagIDs []int
+	// Author matches case-insensitively.
+	Author string
+	// Query keeps news with every word of it in the title or content,
+	// case-insensitively.
+	Query string
+	// Since and Until bound created_at to the half-open range [Since, Until).
+	Since *time.Time
+	Until *time.Time
+	// Sort defaults to newest first. Relevance ranks by the number of TagIDs
+	// an article has, then newest first.
+	Sort NewsSort
+	// Fields restricts the loaded columns.
+	Fields NewsFields
+	// ViewerID hides the news muted by that user.
+	ViewerID string
+	// AfterID and MaxID bound the news ids to the range (AfterID, MaxID].
+	AfterID int
+	MaxID   int
+}
+
+// QueryWords splits a text query into the lowercase words news must contain.
+func QueryWords(query string) []string {
+	return strings.Fields(strings.ToLower(query))
+}
+
+// escapeLike escapes the LIKE wildcards in s, for patterns using ESCAPE '\'.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// queryArgs collects positional query arguments, handing out their
+// placeholders.
+type queryArgs []any
+
+func (a *queryArgs) add(v any) string {
+	*a = append(*a, v)
+	return fmt.Sprintf("$%d", len(*a))
+}
+
+func (a *queryArgs) list(ids []int) string {
+	placeholders := make([]string, 0, len(ids))
+	for _, id := range ids {
+		placeholders = append(placeholders, a.add(id))
+	}
+	return strings.Join(placeholders, ", ")
+}
+
+// newsFilterConditions translates the filter into WHERE conditions on the
+// news table aliased as n, and an ORDER BY clause.
+func newsFilterConditions(filter NewsFilter, args *queryArgs) ([]string, string) {
+	var conditions []string
+	relevance := "0"
+
+	if len(filter.TagIDs) > 0 {
+		tagList := args.list(filter.TagIDs)
+		matched := fmt.Sprintf(
+			"(SELECT COUNT(*) FROM news_tags m WHERE m.news_id = n.id AND m.tag_id IN (%v))", tagList,
+		)
+		if filter.MatchAllTags {
+			conditions = append(conditions, fmt.Sprintf("%v = %d", matched, len(filter.TagIDs)))
+		} else {
+			conditions = append(conditions, matched+" > 0")
+		}
+		relevance = matched
+	}
+	if len(filter.ExcludeTagIDs) > 0 {
+		conditions = append(conditions, fmt.Sprintf(
+			"NOT EXISTS (SELECT 1 FROM news_tags x WHERE x.news_id = n.id AND x.tag_id IN (%v))",
+			args.list(filter.ExcludeTagIDs),
+		))
+	}
+	if filter.Author != "" {
+		conditions = append(conditions, "LOWER(n.author) = LOWER("+args.add(filter.Author)+")")
+	}
+	for _, word := range QueryWords(filter.Query) {
+		pattern := args.add("%" + escapeLike(word) + "%")
+		conditions = append(conditions, fmt.Sprintf(
+			`(LOWER(n.title) LIKE %[1]v ESCAPE '\' OR LOWER(n.content) LIKE %[1]v ESCAPE '\')`, pattern,
+		))
+	}
+	if filter.Since != nil {
+		conditions = append(conditions, "n.created_at >= "+args.add(filter.Since.UTC()))
+	}
+	if filter.Until != nil {
+		conditions = append(conditions, "n.created_at < "+args.add(filter.Until.UTC()))
+	}
+	if filter.ViewerID != "" {
+		conditions = append(conditions, mutedCondition(args.add(filter.ViewerID)))
+	}
+	if filter.AfterID > 0 {
+		conditions = append(conditions, "n.id > "+args.add(filter.AfterID))
+	}
+	if filter.MaxID > 0 {
+		conditions = append(conditions, "n.id <= "+args.add(filter.MaxID))
+	}
+
+	orderBy := "n.created_at DESC, n.id DESC"
+	switch filter.Sort {
+	case NewsSortOldest:
+		orderBy = "n.created_at ASC, n.id ASC"
+	case NewsSortRelevance:
+		orderBy = relevance + " DESC, n.created_at DESC, n.id DESC"
+	}
+	return conditions, orderBy
+}
+
+func (r *SQLRepository) SearchNews(ctx context.Context, filter NewsFilter) ([]News, error) {
+	var args queryArgs
+	conditions, orderBy := newsFilterConditions(filter, &args)
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	if filter.Fields.OmitTags {
+		query := fmt.Sprintf(`
+			SELECT %v
+			FROM news n
+			%v
+			ORDER BY %v
+		`, filter.Fields.selectList(), where, orderBy)
+
+		news := make([]News, 0)
+		if err := r.db.SelectContext(ctx, &news, query, args...); err != nil {
+			return nil, fmt.Errorf("failed to search news: %w", err)
+		}
+		return news, nil
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %v, t.id AS tag_id, t.name AS tag_name, t.slug AS tag_slug
+		FROM news n
+		LEFT JOIN news_tags nt ON n.id = nt.news_id
+		LEFT JOIN tags t ON nt.tag_id = t.id
+		%v
+		ORDER BY %v, t.id
+	`, filter.Fields.selectList(), where, orderBy)
+
+	var newsWithTags []NewsWithTags
+	if err := r.db.SelectContext(ctx, &newsWithTags, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to search news: %w", err)
+	}
+
+	return combineNewsWithTags(newsWithTags), nil
+}
@@ -0,0 +1,102 @@
+package database
+
+import (
+	"context"
+	"time"
+)
+
+// AddInteraction records that the user read or bookmarked a news item,
+// refreshing the timestamp when it was already recorded.
+func (r *SQLRepository) AddInteraction(ctx context.Context, userID string, newsID int, kind InteractionKind) error {
+	query := `
+		INSERT INTO user_news_interactions (user_id, news_id, kind)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, news_id, kind) DO UPDATE
+		SET created_at = CURRENT_TIMESTAMP
+	`
+	_, err := r.db.ExecContext(ctx, query, userID, newsID, kind)
+	return err
+}
+
+func (r *SQLRepository) RemoveInteraction(ctx context.Context, userID string, newsID int, kind InteractionKind) error {
+	query := `DELETE FROM user_news_interactions WHERE user_id = $1 AND news_id = $2 AND kind = $3`
+	_, err := r.db.ExecContext(ctx, query, userID, newsID, kind)
+	return err
+}
+
+// GetInteractions returns the user's interactions, most recent first.
+func (r *SQLRepository) GetInteractions(ctx context.Context, userID string) ([]Interaction, error) {
+	query := `
+		SELECT * FROM user_news_interactions
+		WHERE user_id = $1
+		ORDER BY created_at DESC, news_id DESC, kind
+	`
+	var interactions []Interaction
+	err := r.db.SelectContext(ctx, &interactions, query, userID)
+	return interactions, err
+}
+
+// GetInteractionCounts counts, per news item and kind, the interactions
+// recorded since the given time.
+func (r *SQLRepository) GetInteractionCounts(ctx context.Context, since time.Time) ([]InteractionCount, error) {
+	query := `
+		SELECT news_id, kind, COUNT(*) AS count
+		FROM user_news_interactions
+		WHERE created_at >= $1
+		GROUP BY news_id, kind
+		ORDER BY news_id, kind
+	`
+	var counts []InteractionCount
+	err := r.db.SelectContext(ctx, &counts, query, since.UTC())
+	return counts, err
+}
+
+func (r *SQLRepository) GetTagNewsCounts(ctx context.Context) (map[int]int, error) {
+	query := `SELECT tag_id, COUNT(*) AS count FROM news_tags GROUP BY tag_id`
+	var rows []struct {
+		TagID int `db:"tag_id"`
+		Count int `db:"count"`
+	}
+	if err := r.db.SelectContext(ctx, &rows, query); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[int]int, len(rows))
+	for _, row := range rows {
+		counts[row.TagID] = row.Count
+	}
+	return counts, nil
+}
+
+// GetTagCooccurrence counts, for every ordered pair of distinct tags, the news
+// carrying both.
+func (r *SQLRepository) GetTagCooccurrence(ctx context.Context) ([]TagPair, error) {
+	query := `
+		SELECT a.tag_id, b.tag_id AS other_tag_id, COUNT(*) AS count
+		FROM news_tags a
+		JOIN news_tags b ON a.news_id = b.news_id AND a.tag_id <> b.tag_id
+		GROUP BY a.tag_id, b.tag_id
+		ORDER BY a.tag_id, b.tag_id
+	`
+	var pairs []TagPair
+	err := r.db.SelectContext(ctx, &pairs, query)
+	return pairs, err
+}
+
+// GetTagFavoriteCounts counts, per tag, the users following it.
+func (r *SQLRepository) GetTagFavoriteCounts(ctx context.Context) (map[int]int, error) {
+	query := `SELECT tag_id, COUNT(*) AS count FROM user_favorite_tags GROUP BY tag_id`
+	var rows []struct {
+		TagID int `db:"tag_id"`
+		Count int `db:"count"`
+	}
+	if err := r.db.SelectContext(ctx, &rows, query); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[int]int, len(rows))
+	for _, row := range rows {
+		counts[row.TagID] = row.Count
+	}
+	return counts, nil
+}
@@ -0,0 +1,1122 @@
+package database
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryRepository is a thread-safe in-memory Repository with the same
+// semantics as SQLRepository. It is meant for tests and demos.
+type MemoryRepository struct {
+	mu sync.RWMutex
+
+	users        map[string]User
+	tags         map[int]Tag
+	news         map[int]News
+	newsTags     map[int]map[int]float64
+	favoriteTags map[string]map[int]struct{}
+	interactions map[string][]Interaction
+	mutes        map[string]map[Mute]struct{}
+	tagAliases   map[string]int
+	fingerprints map[int]Fingerprint
+	clusters     map[int]StoryCluster
+	searches     map[int]SavedSearch
+	matches      map[int][]SearchMatch
+	searched     map[int]struct{}
+	deletions    []AccountDeletion
+	auditEvents  []AuditEvent
+
+	nextTagID     int
+	nextNewsID    int
+	nextClusterID int
+	nextSearchID  int
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		users:         make(map[string]User),
+		tags:          make(map[int]Tag),
+		news:          make(map[int]News),
+		newsTags:      make(map[int]map[int]float64),
+		favoriteTags:  make(map[string]map[int]struct{}),
+		interactions:  make(map[string][]Interaction),
+		mutes:         make(map[string]map[Mute]struct{}),
+		tagAliases:    make(map[string]int),
+		fingerprints:  make(map[int]Fingerprint),
+		clusters:      make(map[int]StoryCluster),
+		searches:      make(map[int]SavedSearch),
+		matches:       make(map[int][]SearchMatch),
+		searched:      make(map[int]struct{}),
+		nextTagID:     1,
+		nextNewsID:    1,
+		nextClusterID: 1,
+		nextSearchID:  1,
+	}
+}
+
+func (r *MemoryRepository) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (r *MemoryRepository) GetSchemaVersion(ctx context.Context) (int, error) {
+	return SchemaVersion, nil
+}
+
+func (r *MemoryRepository) UpsertUser(ctx context.Context, user *User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, u := range r.users {
+		if u.Email == user.Email && u.GoogleID != user.GoogleID {
+			return fmt.Errorf("user with email %v already exists", user.Email)
+		}
+	}
+
+	for id, u := range r.users {
+		if u.GoogleID == user.GoogleID {
+			u.Email = user.Email
+			r.users[id] = u
+			user.ID, user.CreatedAt, user.Preferences = u.ID, u.CreatedAt, u.Preferences
+			user.DeletionRequestedAt = u.DeletionRequestedAt
+			return nil
+		}
+	}
+
+	id, err := newUUID()
+	if err != nil {
+		return err
+	}
+	user.ID = id
+	user.CreatedAt = time.Now().UTC()
+	user.Preferences = DefaultPreferences()
+	r.users[id] = *user
+	return nil
+}
+
+func (r *MemoryRepository) GetUserByGoogleID(ctx context.Context, googleID string) (*User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, u := range r.users {
+		if u.GoogleID == googleID {
+			return &u, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *MemoryRepository) GetUserByEmail(ctx context.Context, email string) (*User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, u := range r.users {
+		if u.Email == email {
+			return &u, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *MemoryRepository) GetUserByID(ctx context.Context, id string) (*User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	u, ok := r.users[id]
+	if !ok {
+		return nil, nil
+	}
+	return &u, nil
+}
+
+func (r *MemoryRepository) UpdatePreferences(ctx context.Context, userID string, preferences Preferences) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.users[userID]
+	if !ok {
+		return fmt.Errorf("user %v does not exist", userID)
+	}
+	u.Preferences = preferences
+	r.users[userID] = u
+	return nil
+}
+
+func (r *MemoryRepository) RequestUserDeletion(ctx context.Context, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.users[userID]
+	if !ok || u.DeletionRequestedAt != nil {
+		return nil
+	}
+	now := time.Now().UTC()
+	u.DeletionRequestedAt = &now
+	r.users[userID] = u
+	r.deletions = append(r.deletions, AccountDeletion{
+		ID:          len(r.deletions) + 1,
+		UserID:      userID,
+		RequestedAt: now,
+	})
+	return nil
+}
+
+func (r *MemoryRepository) CancelUserDeletion(ctx context.Context, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if u, ok := r.users[userID]; ok {
+		u.DeletionRequestedAt = nil
+		r.users[userID] = u
+	}
+	now := time.Now().UTC()
+	for i, d := range r.deletions {
+		if d.UserID == userID && d.CancelledAt == nil && d.PurgedAt == nil {
+			r.deletions[i].CancelledAt = &now
+		}
+	}
+	return nil
+}
+
+func (r *MemoryRepository) GetUsersPendingDeletion(ctx context.Context, requestedBefore time.Time) ([]User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var users []User
+	for _, u := range r.users {
+		if u.DeletionRequestedAt != nil && u.DeletionRequestedAt.Before(requestedBefore) {
+			users = append(users, u)
+		}
+	}
+	slices.SortFunc(users, func(a, b User) int {
+		if c := a.DeletionRequestedAt.Compare(*b.DeletionRequestedAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return users, nil
+}
+
+func (r *MemoryRepository) PurgeUser(ctx context.Context, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.users, userID)
+	delete(r.favoriteTags, userID)
+	delete(r.interactions, userID)
+	delete(r.mutes, userID)
+	for id, search := range r.searches {
+		if search.UserID == userID {
+			delete(r.searches, id)
+			delete(r.matches, id)
+		}
+	}
+	now := time.Now().UTC()
+	for i, d := range r.deletions {
+		if d.UserID == userID && d.CancelledAt == nil && d.PurgedAt == nil {
+			r.deletions[i].PurgedAt = &now
+		}
+	}
+	return nil
+}
+
+func (r *MemoryRepository) GetAllTags(ctx context.Context) ([]Tag, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var tags []Tag
+	for _, t := range r.tags {
+		tags = append(tags, t)
+	}
+	sortTags(tags)
+	return tags, nil
+}
+
+func (r *MemoryRepository) GetTagDetail(ctx context.Context, tagID int) (*TagDetail, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tag, ok := r.tags[tagID]
+	if !ok {
+		return nil, nil
+	}
+	detail := &TagDetail{Tag: tag}
+	for newsID, tags := range r.newsTags {
+		if _, ok := tags[tagID]; !ok {
+			continue
+		}
+		detail.NewsCount++
+		createdAt := r.news[newsID].CreatedAt
+		if detail.LatestNewsAt == nil || createdAt.After(*detail.LatestNewsAt) {
+			detail.LatestNewsAt = &createdAt
+		}
+	}
+	for _, tags := range r.favoriteTags {
+		if _, ok := tags[tagID]; ok {
+			detail.FollowerCount++
+		}
+	}
+	return detail, nil
+}
+
+func (r *MemoryRepository) GetNewsByID(ctx context.Context, id int) (*News, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	n, ok := r.news[id]
+	if !ok {
+		return nil, nil
+	}
+	n.Tags = r.tagsForNews(id, nil)
+	return &n, nil
+}
+
+func (r *MemoryRepository) GetNewsByIDs(ctx context.Context, ids []int) ([]News, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	news := r.selectNews(func(n News) bool { return slices.Contains(ids, n.ID) }, nil)
+	return orderNewsByIDs(news, ids), nil
+}
+
+func (r *MemoryRepository) GetAllNews(ctx context.Context) ([]News, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.selectNews(func(n News) bool { return true }, nil), nil
+}
+
+func (r *MemoryRepository) GetNewsByTag(ctx context.Context, tagID int, includeDescendants bool, viewerID string) ([]News, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tree := map[int]struct{}{tagID: {}}
+	if includeDescendants {
+		tree = r.descendants(tree)
+	}
+	return r.selectNews(func(n News) bool {
+		if r.muted(viewerID, n) {
+			return false
+		}
+		for id := range tree {
+			if _, ok := r.newsTags[n.ID][id]; ok {
+				return true
+			}
+		}
+		return false
+	}, nil), nil
+}
+
+func (r *MemoryRepository) GetTagsForNews(ctx context.Context, newsID int) ([]Tag, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.tagsForNews(newsID, nil), nil
+}
+
+func (r *MemoryRepository) AddFavoriteTag(ctx context.Context, userID string, tagID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[userID]; !ok {
+		return fmt.Errorf("user %v does not exist", userID)
+	}
+	if _, ok := r.tags[tagID]; !ok {
+		return fmt.Errorf("tag %v does not exist", tagID)
+	}
+	if r.favoriteTags[userID] == nil {
+		r.favoriteTags[userID] = make(map[int]struct{})
+	}
+	r.favoriteTags[userID][tagID] = struct{}{}
+	return nil
+}
+
+func (r *MemoryRepository) RemoveFavoriteTag(ctx context.Context, userID string, tagID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.favoriteTags[userID], tagID)
+	return nil
+}
+
+func (r *MemoryRepository) GetFavoriteTags(ctx context.Context, userID string) ([]Tag, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var tags []Tag
+	for tagID := range r.favoriteTags[userID] {
+		tags = append(tags, r.tags[tagID])
+	}
+	sortTags(tags)
+	return tags, nil
+}
+
+// GetFavoriteNews mirrors the SQL join, so every news item only carries the
+// tags the user follows.
+func (r *MemoryRepository) GetFavoriteNews(ctx context.Context, userID string, includeDescendants bool) ([]News, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	favorites := r.favoriteTags[userID]
+	if favorites == nil {
+		favorites = map[int]struct{}{}
+	}
+	if includeDescendants {
+		favorites = r.descendants(favorites)
+	}
+	news := r.selectNews(func(n News) bool {
+		if r.muted(userID, n) {
+			return false
+		}
+		for tagID := range r.newsTags[n.ID] {
+			if _, ok := favorites[tagID]; ok {
+				return true
+			}
+		}
+		return false
+	}, favorites)
+	return news, nil
+}
+
+func (r *MemoryRepository) SearchNews(ctx context.Context, filter NewsFilter) ([]News, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	matchedTags := func(n News) int {
+		count := 0
+		for _, tagID := range filter.TagIDs {
+			if _, ok := r.newsTags[n.ID][tagID]; ok {
+				count++
+			}
+		}
+		return count
+	}
+
+	news := r.selectNews(func(n News) bool {
+		if len(filter.TagIDs) > 0 {
+			matched := matchedTags(n)
+			if matched == 0 || (filter.MatchAllTags && matched != len(filter.TagIDs)) {
+				return false
+			}
+		}
+		for _, tagID := range filter.ExcludeTagIDs {
+			if _, ok := r.newsTags[n.ID][tagID]; ok {
+				return false
+			}
+		}
+		if filter.Author != "" && !strings.EqualFold(n.Author, filter.Author) {
+			return false
+		}
+		for _, word := range QueryWords(filter.Query) {
+			if !strings.Contains(strings.ToLower(n.Title), word) && !strings.Contains(strings.ToLower(n.Content), word) {
+				return false
+			}
+		}
+		if filter.Since != nil && n.CreatedAt.Before(*filter.Since) {
+			return false
+		}
+		if filter.Until != nil && !n.CreatedAt.Before(*filter.Until) {
+			return false
+		}
+		if r.muted(filter.ViewerID, n) {
+			return false
+		}
+		if n.ID <= filter.AfterID || (filter.MaxID > 0 && n.ID > filter.MaxID) {
+			return false
+		}
+		return true
+	}, nil)
+
+	for i := range news {
+		news[i] = filter.Fields.apply(news[i])
+	}
+
+	switch filter.Sort {
+	case NewsSortOldest:
+		slices.Reverse(news)
+	case NewsSortRelevance:
+		slices.SortStableFunc(news, func(a, b News) int {
+			return cmp.Compare(matchedTags(b), matchedTags(a))
+		})
+	}
+	return news, nil
+}
+
+func (r *MemoryRepository) GetMutes(ctx context.Context, userID string) ([]Mute, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var mutes []Mute
+	for mute := range r.mutes[userID] {
+		if mute.Kind == MuteTag {
+			mute.Value = r.tags[mute.TagID].Name
+		}
+		mutes = append(mutes, mute)
+	}
+	slices.SortFunc(mutes, func(a, b Mute) int {
+		if c := cmp.Compare(b.Kind, a.Kind); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Value, b.Value)
+	})
+	return mutes, nil
+}
+
+func (r *MemoryRepository) AddMute(ctx context.Context, userID string, mute Mute) error {
+	mute, err := normalizeMute(mute)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[userID]; !ok {
+		return fmt.Errorf("user %v does not exist", userID)
+	}
+	if _, ok := r.tags[mute.TagID]; mute.Kind == MuteTag && !ok {
+		return fmt.Errorf("tag %v does not exist", mute.TagID)
+	}
+	if r.mutes[userID] == nil {
+		r.mutes[userID] = make(map[Mute]struct{})
+	}
+	r.mutes[userID][mute] = struct{}{}
+	return nil
+}
+
+func (r *MemoryRepository) RemoveMute(ctx context.Context, userID string, mute Mute) error {
+	mute, err := normalizeMute(mute)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.mutes[userID], mute)
+	return nil
+}
+
+// muted reports whether the user muted the news item. Callers hold the lock.
+func (r *MemoryRepository) muted(userID string, n News) bool {
+	for mute := range r.mutes[userID] {
+		switch mute.Kind {
+		case MuteTag:
+			if _, ok := r.newsTags[n.ID][mute.TagID]; ok {
+				return true
+			}
+		case MuteAuthor:
+			if strings.ToLower(n.Author) == mute.Value {
+				return true
+			}
+		case MuteSource:
+			if strings.ToLower(n.Source) == mute.Value {
+				return true
+			}
+		case MuteKeyword:
+			if strings.Contains(strings.ToLower(n.Title), mute.Value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (r *MemoryRepository) AddInteraction(ctx context.Context, userID string, newsID int, kind InteractionKind) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[userID]; !ok {
+		return fmt.Errorf("user %v does not exist", userID)
+	}
+	if _, ok := r.news[newsID]; !ok {
+		return fmt.Errorf("news %v does not exist", newsID)
+	}
+
+	interactions := slices.DeleteFunc(r.interactions[userID], func(i Interaction) bool {
+		return i.NewsID == newsID && i.Kind == kind
+	})
+	r.interactions[userID] = append(interactions, Interaction{
+		UserID:    userID,
+		NewsID:    newsID,
+		Kind:      kind,
+		CreatedAt: time.Now().UTC(),
+	})
+	return nil
+}
+
+func (r *MemoryRepository) RemoveInteraction(ctx context.Context, userID string, newsID int, kind InteractionKind) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.interactions[userID] = slices.DeleteFunc(r.interactions[userID], func(i Interaction) bool {
+		return i.NewsID == newsID && i.Kind == kind
+	})
+	return nil
+}
+
+func (r *MemoryRepository) GetInteractions(ctx context.Context, userID string) ([]Interaction, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if len(r.interactions[userID]) == 0 {
+		return nil, nil
+	}
+	interactions := slices.Clone(r.interactions[userID])
+	slices.SortFunc(interactions, func(a, b Interaction) int {
+		if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(b.NewsID, a.NewsID); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Kind, b.Kind)
+	})
+	return interactions, nil
+}
+
+func (r *MemoryRepository) GetInteractionCounts(ctx context.Context, since time.Time) ([]InteractionCount, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	type key struct {
+		newsID int
+		kind   InteractionKind
+	}
+	counts := make(map[key]int)
+	for _, interactions := range r.interactions {
+		for _, i := range interactions {
+			if !i.CreatedAt.Before(since) {
+				counts[key{i.NewsID, i.Kind}]++
+			}
+		}
+	}
+
+	var result []InteractionCount
+	for k, count := range counts {
+		result = append(result, InteractionCount{NewsID: k.newsID, Kind: k.kind, Count: count})
+	}
+	slices.SortFunc(result, func(a, b InteractionCount) int {
+		if c := cmp.Compare(a.NewsID, b.NewsID); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Kind, b.Kind)
+	})
+	return result, nil
+}
+
+func (r *MemoryRepository) GetTagNewsCounts(ctx context.Context) (map[int]int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	counts := make(map[int]int)
+	for _, tags := range r.newsTags {
+		for tagID := range tags {
+			counts[tagID]++
+		}
+	}
+	return counts, nil
+}
+
+func (r *MemoryRepository) GetTagCooccurrence(ctx context.Context) ([]TagPair, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	counts := make(map[[2]int]int)
+	for _, tags := range r.newsTags {
+		for a := range tags {
+			for b := range tags {
+				if a != b {
+					counts[[2]int{a, b}]++
+				}
+			}
+		}
+	}
+
+	var pairs []TagPair
+	for pair, count := range counts {
+		pairs = append(pairs, TagPair{TagID: pair[0], OtherTagID: pair[1], Count: count})
+	}
+	slices.SortFunc(pairs, func(a, b TagPair) int {
+		if c := cmp.Compare(a.TagID, b.TagID); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.OtherTagID, b.OtherTagID)
+	})
+	return pairs, nil
+}
+
+func (r *MemoryRepository) GetUnfingerprintedNews(ctx context.Context, limit int) ([]News, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	news := make([]News, 0)
+	for _, n := range r.news {
+		if _, ok := r.fingerprints[n.ID]; !ok {
+			news = append(news, n)
+		}
+	}
+	slices.SortFunc(news, func(a, b News) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+	if len(news) > limit {
+		news = news[:limit]
+	}
+	return news, nil
+}
+
+func (r *MemoryRepository) GetFingerprints(ctx context.Context, since, until time.Time) ([]Fingerprint, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var fingerprints []Fingerprint
+	for _, f := range r.fingerprints {
+		f.CreatedAt = r.news[f.NewsID].CreatedAt
+		if !f.CreatedAt.Before(since) && f.CreatedAt.Before(until) {
+			fingerprints = append(fingerprints, f)
+		}
+	}
+	slices.SortFunc(fingerprints, func(a, b Fingerprint) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.NewsID, b.NewsID)
+	})
+	return fingerprints, nil
+}
+
+func (r *MemoryRepository) AddFingerprint(ctx context.Context, fingerprint *Fingerprint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.news[fingerprint.NewsID]; !ok {
+		return fmt.Errorf("news %v does not exist", fingerprint.NewsID)
+	}
+	if _, ok := r.fingerprints[fingerprint.NewsID]; ok {
+		return fmt.Errorf("news %v already has a fingerprint", fingerprint.NewsID)
+	}
+	if fingerprint.ClusterID == 0 {
+		r.clusters[r.nextClusterID] = StoryCluster{ID: r.nextClusterID, RepresentativeID: fingerprint.NewsID}
+		fingerprint.ClusterID = r.nextClusterID
+		r.nextClusterID++
+	} else if _, ok := r.clusters[fingerprint.ClusterID]; !ok {
+		return fmt.Errorf("story cluster %v does not exist", fingerprint.ClusterID)
+	}
+	r.fingerprints[fingerprint.NewsID] = Fingerprint{
+		NewsID:    fingerprint.NewsID,
+		SimHash:   fingerprint.SimHash,
+		ClusterID: fingerprint.ClusterID,
+	}
+	return nil
+}
+
+func (r *MemoryRepository) GetStoryMemberships(ctx context.Context, newsIDs []int) (map[int]StoryMembership, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	sizes := make(map[int]int)
+	for _, f := range r.fingerprints {
+		sizes[f.ClusterID]++
+	}
+	memberships := make(map[int]StoryMembership)
+	for _, id := range newsIDs {
+		f, ok := r.fingerprints[id]
+		if !ok {
+			continue
+		}
+		memberships[id] = StoryMembership{
+			NewsID:           id,
+			ClusterID:        f.ClusterID,
+			RepresentativeID: r.clusters[f.ClusterID].RepresentativeID,
+			Size:             sizes[f.ClusterID],
+		}
+	}
+	return memberships, nil
+}
+
+func (r *MemoryRepository) GetStoryCluster(ctx context.Context, id int) (*StoryCluster, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	cluster, ok := r.clusters[id]
+	if !ok {
+		return nil, nil
+	}
+	cluster.News = r.selectNews(func(n News) bool { return r.fingerprints[n.ID].ClusterID == id }, nil)
+	slices.Reverse(cluster.News)
+	return &cluster, nil
+}
+
+func (r *MemoryRepository) CreateSavedSearch(ctx context.Context, search *SavedSearch) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[search.UserID]; !ok {
+		return fmt.Errorf("user %v does not exist", search.UserID)
+	}
+	for _, tagID := range search.TagIDs {
+		if _, ok := r.tags[tagID]; !ok {
+			return fmt.Errorf("tag %v does not exist", tagID)
+		}
+	}
+	search.ID = r.nextSearchID
+	search.LastNewsID = r.nextNewsID - 1
+	search.CreatedAt = time.Now().UTC()
+	search.TagIDs = slices.Clone(search.TagIDs)
+	if search.TagIDs == nil {
+		search.TagIDs = []int{}
+	}
+	slices.Sort(search.TagIDs)
+	r.searches[search.ID] = *search
+	r.nextSearchID++
+	return nil
+}
+
+func (r *MemoryRepository) GetSavedSearches(ctx context.Context, userID string) ([]SavedSearch, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var searches []SavedSearch
+	for _, search := range r.searches {
+		if search.UserID == userID {
+			searches = append(searches, search)
+		}
+	}
+	slices.SortFunc(searches, func(a, b SavedSearch) int { return cmp.Compare(a.ID, b.ID) })
+	return searches, nil
+}
+
+func (r *MemoryRepository) GetSavedSearch(ctx context.Context, userID string, id int) (*SavedSearch, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	search, ok := r.searches[id]
+	if !ok || search.UserID != userID {
+		return nil, nil
+	}
+	return &search, nil
+}
+
+func (r *MemoryRepository) DeleteSavedSearch(ctx context.Context, userID string, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if search, ok := r.searches[id]; ok && search.UserID == userID {
+		delete(r.searches, id)
+		delete(r.matches, id)
+	}
+	return nil
+}
+
+func (r *MemoryRepository) GetAllSavedSearches(ctx context.Context) ([]SavedSearch, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	searches := slices.Collect(maps.Values(r.searches))
+	slices.SortFunc(searches, func(a, b SavedSearch) int { return cmp.Compare(a.ID, b.ID) })
+	return searches, nil
+}
+
+func (r *MemoryRepository) AddSearchMatches(ctx context.Context, searchID int, newsIDs []int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.searches[searchID]; !ok {
+		return nil
+	}
+	now := time.Now().UTC()
+	for _, newsID := range newsIDs {
+		if slices.ContainsFunc(r.matches[searchID], func(m SearchMatch) bool { return m.NewsID == newsID }) {
+			continue
+		}
+		r.matches[searchID] = append(r.matches[searchID], SearchMatch{SearchID: searchID, NewsID: newsID, MatchedAt: now})
+	}
+	return nil
+}
+
+func (r *MemoryRepository) GetSearchMatches(ctx context.Context, searchID int) ([]SearchMatch, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	matches := slices.Clone(r.matches[searchID])
+	slices.SortFunc(matches, func(a, b SearchMatch) int {
+		if c := b.MatchedAt.Compare(a.MatchedAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.NewsID, a.NewsID)
+	})
+	return matches, nil
+}
+
+func (r *MemoryRepository) GetUnsearchedNewsIDs(ctx context.Context, limit int) ([]int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ids := make([]int, 0)
+	for id := range r.news {
+		if _, ok := r.searched[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+	return ids, nil
+}
+
+func (r *MemoryRepository) MarkNewsSearched(ctx context.Context, newsIDs []int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, id := range newsIDs {
+		r.searched[id] = struct{}{}
+	}
+	return nil
+}
+
+func (r *MemoryRepository) GetTagFavoriteCounts(ctx context.Context) (map[int]int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	counts := make(map[int]int)
+	for _, tags := range r.favoriteTags {
+		for tagID := range tags {
+			counts[tagID]++
+		}
+	}
+	return counts, nil
+}
+
+func (r *MemoryRepository) ResolveTag(ctx context.Context, name string) (*Tag, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	name = normalizeAlias(name)
+	for _, tag := range r.tags {
+		if tag.Slug == name {
+			return &tag, nil
+		}
+	}
+	for _, tag := range r.tags {
+		if strings.ToLower(tag.Name) == name {
+			return &tag, nil
+		}
+	}
+	if id, ok := r.tagAliases[name]; ok {
+		tag := r.tags[id]
+		return &tag, nil
+	}
+	return nil, nil
+}
+
+func (r *MemoryRepository) GetTagAliases(ctx context.Context) ([]TagAlias, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var aliases []TagAlias
+	for alias, tagID := range r.tagAliases {
+		aliases = append(aliases, TagAlias{Alias: alias, TagID: tagID})
+	}
+	slices.SortFunc(aliases, func(a, b TagAlias) int {
+		if c := cmp.Compare(a.TagID, b.TagID); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Alias, b.Alias)
+	})
+	return aliases, nil
+}
+
+func (r *MemoryRepository) SetTagParent(ctx context.Context, tagID int, parentID *int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tags := make([]Tag, 0, len(r.tags))
+	for _, tag := range r.tags {
+		tags = append(tags, tag)
+	}
+	if err := checkTagParent(tags, tagID, parentID); err != nil {
+		return err
+	}
+	tag := r.tags[tagID]
+	tag.ParentID = parentID
+	r.tags[tagID] = tag
+	return nil
+}
+
+func (r *MemoryRepository) AddTagAlias(ctx context.Context, tagID int, alias string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	alias = normalizeAlias(alias)
+	if _, ok := r.tags[tagID]; !ok {
+		return ErrTagNotFound
+	}
+	for _, tag := range r.tags {
+		if strings.ToLower(tag.Name) == alias {
+			return ErrAliasTaken
+		}
+	}
+	if id, ok := r.tagAliases[alias]; ok && id != tagID {
+		return ErrAliasTaken
+	}
+	r.tagAliases[alias] = tagID
+	return nil
+}
+
+func (r *MemoryRepository) RemoveTagAlias(ctx context.Context, alias string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.tagAliases, normalizeAlias(alias))
+	return nil
+}
+
+// descendants returns the tags together with all tags below them.
+func (r *MemoryRepository) descendants(tags map[int]struct{}) map[int]struct{} {
+	tree := maps.Clone(tags)
+	for added := true; added; {
+		added = false
+		for _, tag := range r.tags {
+			if _, ok := tree[tag.ID]; ok || tag.ParentID == nil {
+				continue
+			}
+			if _, ok := tree[*tag.ParentID]; ok {
+				tree[tag.ID] = struct{}{}
+				added = true
+			}
+		}
+	}
+	return tree
+}
+
+func (r *MemoryRepository) AddAuditEvent(ctx context.Context, event *AuditEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	event.ID = int64(len(r.auditEvents) + 1)
+	event.OccurredAt = time.Now().UTC()
+	r.auditEvents = append(r.auditEvents, *event)
+	return nil
+}
+
+func (r *MemoryRepository) GetAuditEvents(ctx context.Context, filter AuditFilter) ([]AuditEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var events []AuditEvent
+	for i := len(r.auditEvents) - 1; i >= 0; i-- {
+		if filter.Limit > 0 && len(events) == filter.Limit {
+			break
+		}
+		if filter.matches(r.auditEvents[i]) {
+			events = append(events, r.auditEvents[i])
+		}
+	}
+	return events, nil
+}
+
+// CreateTag inserts a tag, or returns the existing one with the same name.
+func (r *MemoryRepository) CreateTag(ctx context.Context, name, description string) (Tag, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, t := range r.tags {
+		if t.Name == name {
+			return t, nil
+		}
+	}
+	tag := Tag{ID: r.nextTagID, Name: name, Slug: Slugify(name), Description: description}
+	r.tags[tag.ID] = tag
+	r.nextTagID++
+	return tag, nil
+}
+
+// CreateNews inserts a news item, filling in its ID and, when unset, its
+// creation time.
+func (r *MemoryRepository) CreateNews(ctx context.Context, news *News) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	news.ID = r.nextNewsID
+	if news.CreatedAt.IsZero() {
+		news.CreatedAt = time.Now().UTC()
+	}
+	stored := *news
+	stored.Tags = nil
+	r.news[news.ID] = stored
+	r.nextNewsID++
+	return nil
+}
+
+func (r *MemoryRepository) AddTagsToNews(ctx context.Context, newsID int, tags []TagAssignment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.news[newsID]; !ok {
+		return fmt.Errorf("news %v does not exist", newsID)
+	}
+	for _, tag := range tags {
+		if _, ok := r.tags[tag.TagID]; !ok {
+			return fmt.Errorf("tag %v does not exist", tag.TagID)
+		}
+	}
+	if r.newsTags[newsID] == nil {
+		r.newsTags[newsID] = make(map[int]float64)
+	}
+	for _, tag := range tags {
+		if confidence, ok := r.newsTags[newsID][tag.TagID]; !ok || tag.Confidence > confidence {
+			r.newsTags[newsID][tag.TagID] = tag.Confidence
+		}
+	}
+	return nil
+}
+
+// selectNews returns the matching news ordered newest first. When only is
+// non-nil, the attached tags are restricted to those ids.
+func (r *MemoryRepository) selectNews(match func(News) bool, only map[int]struct{}) []News {
+	result := make([]News, 0)
+	for _, n := range r.news {
+		if !match(n) {
+			continue
+		}
+		n.Tags = r.tagsForNews(n.ID, only)
+		if n.Tags == nil {
+			n.Tags = []Tag{}
+		}
+		result = append(result, n)
+	}
+	slices.SortFunc(result, func(a, b News) int {
+		if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.ID, a.ID)
+	})
+	return result
+}
+
+func (r *MemoryRepository) tagsForNews(newsID int, only map[int]struct{}) []Tag {
+	var tags []Tag
+	for tagID := range r.newsTags[newsID] {
+		if only != nil {
+			if _, ok := only[tagID]; !ok {
+				continue
+			}
+		}
+		tags = append(tags, r.tags[tagID])
+	}
+	sortTags(tags)
+	return tags
+}
+
+func sortTags(tags []Tag) {
+	slices.SortFunc(tags, func(a, b Tag) int { return cmp.Compare(a.ID, b.ID) })
+}
@@ -0,0 +1,8 @@
+ALTER TABLE tags ADD COLUMN slug TEXT NOT NULL DEFAULT '';
+ALTER TABLE tags ADD COLUMN description TEXT NOT NULL DEFAULT '';
+
+UPDATE tags SET slug = LOWER(REPLACE(TRIM(name), ' ', '-'));
+
+CREATE UNIQUE INDEX tags_slug_idx ON tags (slug);
+
+INSERT INTO schema_migrations (version) VALUES (7) ON CONFLICT DO NOTHING;
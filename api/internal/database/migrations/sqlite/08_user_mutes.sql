@@ -0,0 +1,14 @@
+CREATE TABLE user_muted_tags (
+    user_id TEXT REFERENCES users(id) ON DELETE CASCADE,
+    tag_id INTEGER REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (user_id, tag_id)
+);
+
+CREATE TABLE user_muted_terms (
+    user_id TEXT REFERENCES users(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL CHECK (kind IN ('author', 'keyword')),
+    value TEXT NOT NULL,
+    PRIMARY KEY (user_id, kind, value)
+);
+
+INSERT INTO schema_migrations (version) VALUES (8) ON CONFLICT DO NOTHING;
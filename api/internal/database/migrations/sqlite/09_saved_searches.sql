@@ -0,0 +1,27 @@
+CREATE TABLE saved_searches (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    query TEXT NOT NULL DEFAULT '',
+    match_all_tags BOOLEAN NOT NULL DEFAULT FALSE,
+    window_days INTEGER NOT NULL DEFAULT 0,
+    last_news_id INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE saved_search_tags (
+    search_id INTEGER REFERENCES saved_searches(id) ON DELETE CASCADE,
+    tag_id INTEGER REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (search_id, tag_id)
+);
+
+CREATE TABLE saved_search_matches (
+    search_id INTEGER REFERENCES saved_searches(id) ON DELETE CASCADE,
+    news_id INTEGER REFERENCES news(id) ON DELETE CASCADE,
+    matched_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (search_id, news_id)
+);
+
+CREATE INDEX saved_searches_user_id_idx ON saved_searches (user_id);
+
+INSERT INTO schema_migrations (version) VALUES (9) ON CONFLICT DO NOTHING;
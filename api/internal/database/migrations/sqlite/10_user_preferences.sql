@@ -0,0 +1,3 @@
+ALTER TABLE users ADD COLUMN preferences TEXT NOT NULL DEFAULT '{}';
+
+INSERT INTO schema_migrations (version) VALUES (10) ON CONFLICT DO NOTHING;
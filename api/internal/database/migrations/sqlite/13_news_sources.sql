@@ -0,0 +1,15 @@
+-- source is the publication a news item comes from, e.g. "The Verge".
+ALTER TABLE news ADD COLUMN source TEXT NOT NULL DEFAULT '';
+
+-- SQLite cannot alter a CHECK constraint, so user_muted_terms is rebuilt.
+CREATE TABLE user_muted_terms_new (
+    user_id TEXT REFERENCES users(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL CHECK (kind IN ('author', 'keyword', 'source')),
+    value TEXT NOT NULL,
+    PRIMARY KEY (user_id, kind, value)
+);
+INSERT INTO user_muted_terms_new SELECT user_id, kind, value FROM user_muted_terms;
+DROP TABLE user_muted_terms;
+ALTER TABLE user_muted_terms_new RENAME TO user_muted_terms;
+
+INSERT INTO schema_migrations (version) VALUES (13) ON CONFLICT DO NOTHING;
@@ -0,0 +1,13 @@
+-- searched_news records the news checked against the saved searches. Serial
+-- ids are not committed in order, so news are tracked explicitly instead of
+-- by the highest id checked.
+CREATE TABLE searched_news (
+    news_id INTEGER PRIMARY KEY REFERENCES news(id) ON DELETE CASCADE
+);
+
+-- news up to the oldest search cursor were already checked
+INSERT INTO searched_news (news_id)
+SELECT id FROM news
+WHERE id <= (SELECT COALESCE(MIN(last_news_id), (SELECT MAX(id) FROM news)) FROM saved_searches);
+
+INSERT INTO schema_migrations (version) VALUES (14) ON CONFLICT DO NOTHING;
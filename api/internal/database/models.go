@@ -5,15 +5,46 @@ import (
 )
 
 type User struct {
-	ID        string    `db:"id"`
-	GoogleID  string    `db:"google_id"`
-	Email     string    `db:"email"`
-	CreatedAt time.Time `db:"created_at"`
+	ID          string      `db:"id"`
+	GoogleID    string      `db:"google_id"`
+	Email       string      `db:"email"`
+	CreatedAt   time.Time   `db:"created_at"`
+	Preferences Preferences `db:"preferences"`
+	// DeletionRequestedAt is set while the user waits to be purged.
+	DeletionRequestedAt *time.Time `db:"deletion_requested_at"`
 }
 
 type Tag struct {
 	ID   int    `db:"id" json:"id"`
 	Name string `db:"name" json:"name"`
+	// Slug is a URL-safe name that, unlike ID, is the same in every
+	// environment.
+	Slug        string `db:"slug" json:"slug"`
+	Description string `db:"description" json:"description,omitempty"`
+	ParentID    *int   `db:"parent_id" json:"parent_id,omitempty"`
+}
+
+// TagDetail is a tag with statistics on its use.
+type TagDetail struct {
+	Tag
+	NewsCount     int        `db:"news_count" json:"news_count"`
+	FollowerCount int        `db:"follower_count" json:"follower_count"`
+	LatestNewsAt  *time.Time `db:"latest_news_at" json:"latest_news_at"`
+}
+
+// TagAlias is another name of a tag, e.g. k8s for kubernetes. Aliases are
+// stored lowercase.
+type TagAlias struct {
+	Alias string `db:"alias" json:"alias"`
+	TagID int    `db:"tag_id" json:"tag_id"`
+}
+
+// TagNode is a tag in the taxonomy with its aliases and child tags.
+type TagNode struct {
+	ID       int       `json:"id"`
+	Name     string    `json:"name"`
+	Aliases  []string  `json:"aliases"`
+	Children []TagNode `json:"children"`
 }
 
 type News struct {
@@ -21,12 +52,146 @@ type News struct {
 	Title     string    `db:"title"`
 	Content   string    `db:"content"`
 	Author    string    `db:"author"`
+	Source    string    `db:"source"`
 	CreatedAt time.Time `db:"created_at"`
 	Tags      []Tag     `db:"-"`
+	// StoryID and RelatedCount are set when a list is collapsed to one news
+	// item per story cluster.
+	StoryID      int `db:"-" json:"StoryID,omitempty"`
+	RelatedCount int `db:"-" json:"RelatedCount,omitempty"`
+}
+
+// TagAssignment tags a news item with the given confidence, 1 for tags set by
+// the fetcher and lower for tags inferred from the content.
+type TagAssignment struct {
+	TagID      int
+	Confidence float64
 }
 
 type NewsWithTags struct {
 	News
 	TagID   *int    `db:"tag_id"`
 	TagName *string `db:"tag_name"`
+	TagSlug *string `db:"tag_slug"`
+}
+
+type InteractionKind string
+
+const (
+	InteractionRead     InteractionKind = "read"
+	InteractionBookmark InteractionKind = "bookmark"
+)
+
+type Interaction struct {
+	UserID    string          `db:"user_id" json:"-"`
+	NewsID    int             `db:"news_id" json:"news_id"`
+	Kind      InteractionKind `db:"kind" json:"kind"`
+	CreatedAt time.Time       `db:"created_at" json:"created_at"`
+}
+
+// InteractionCount counts the interactions of one kind with a news item.
+type InteractionCount struct {
+	NewsID int             `db:"news_id"`
+	Kind   InteractionKind `db:"kind"`
+	Count  int             `db:"count"`
+}
+
+type MuteKind string
+
+const (
+	MuteTag     MuteKind = "tag"
+	MuteAuthor  MuteKind = "author"
+	MuteKeyword MuteKind = "keyword"
+	MuteSource  MuteKind = "source"
+)
+
+// Mute hides news from a user: news tagged with TagID, written by an author,
+// published by a source or with a keyword in their title. Authors, sources
+// and keywords match case-insensitively and are stored lowercase.
+type Mute struct {
+	Kind  MuteKind `db:"kind" json:"kind"`
+	TagID int      `db:"tag_id" json:"tag_id,omitempty"`
+	// Value is the author, source or keyword, or the tag name for tag mutes.
+	Value string `db:"value" json:"value"`
+}
+
+// AccountDeletion records a user's request to delete their account and what
+// became of it.
+type AccountDeletion struct {
+	ID          int        `db:"id"`
+	UserID      string     `db:"user_id"`
+	RequestedAt time.Time  `db:"requested_at"`
+	CancelledAt *time.Time `db:"cancelled_at"`
+	PurgedAt    *time.Time `db:"purged_at"`
+}
+
+// AuditEvent records who did what to which object, and from where.
+type AuditEvent struct {
+	ID         int64     `db:"id" json:"id"`
+	OccurredAt time.Time `db:"occurred_at" json:"occurred_at"`
+	// ActorID is the user id, or names the administrator or the system.
+	ActorID    string `db:"actor_id" json:"actor_id"`
+	Action     string `db:"action" json:"action"`
+	TargetType string `db:"target_type" json:"target_type,omitempty"`
+	TargetID   string `db:"target_id" json:"target_id,omitempty"`
+	IP         string `db:"ip" json:"ip,omitempty"`
+	UserAgent  string `db:"user_agent" json:"user_agent,omitempty"`
+	RequestID  string `db:"request_id" json:"request_id,omitempty"`
+}
+
+// SavedSearch is a news filter a user stored to run again and to be alerted
+// of new news matching it.
+type SavedSearch struct {
+	ID     int    `db:"id" json:"id"`
+	UserID string `db:"user_id" json:"-"`
+	Name   string `db:"name" json:"name"`
+	// Query lists words that must all appear in the title or content.
+	Query        string `db:"query" json:"query"`
+	TagIDs       []int  `db:"-" json:"tags"`
+	MatchAllTags bool   `db:"match_all_tags" json:"match_all_tags"`
+	// WindowDays limits the results to news from the last days, 0 meaning
+	// no limit.
+	WindowDays int `db:"window_days" json:"window_days"`
+	// LastNewsID is the newest news item when the search was saved, only
+	// later news being matched against it.
+	LastNewsID int       `db:"last_news_id" json:"-"`
+	CreatedAt  time.Time `db:"created_at" json:"created_at"`
+}
+
+// SearchMatch records that a news item ingested after a search was saved
+// matched it.
+type SearchMatch struct {
+	SearchID  int       `db:"search_id" json:"search_id"`
+	NewsID    int       `db:"news_id" json:"news_id"`
+	MatchedAt time.Time `db:"matched_at" json:"matched_at"`
+}
+
+// TagPair counts the news tagged with both TagID and OtherTagID.
+type TagPair struct {
+	TagID      int `db:"tag_id"`
+	OtherTagID int `db:"other_tag_id"`
+	Count      int `db:"count"`
+}
+
+// Fingerprint is the SimHash of a news item and the story cluster it was
+// assigned to. CreatedAt is the creation time of the news item.
+type Fingerprint struct {
+	NewsID    int       `db:"news_id"`
+	SimHash   int64     `db:"simhash"`
+	ClusterID int       `db:"cluster_id"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// StoryMembership places a news item in its story cluster of Size news.
+type StoryMembership struct {
+	NewsID           int `db:"news_id"`
+	ClusterID        int `db:"cluster_id"`
+	RepresentativeID int `db:"representative_id"`
+	Size             int `db:"size"`
+}
+
+type StoryCluster struct {
+	ID               int    `db:"id" json:"id"`
+	RepresentativeID int    `db:"representative_id" json:"representative_id"`
+	News             []News `db:"-" json:"news"`
 }
@@ -0,0 +1,89 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// mutedCondition is a WHERE condition on the news table aliased as n that
+// drops the news muted by the user whose id is bound to the placeholder.
+func mutedCondition(placeholder string) string {
+	return fmt.Sprintf(`NOT EXISTS (
+		SELECT 1 FROM news_tags mt
+		JOIN user_muted_tags um ON um.tag_id = mt.tag_id
+		WHERE mt.news_id = n.id AND um.user_id = %[1]v
+	) AND NOT EXISTS (
+		SELECT 1 FROM user_muted_terms um
+		WHERE um.user_id = %[1]v AND (
+			(um.kind = 'author' AND LOWER(n.author) = um.value)
+			OR (um.kind = 'source' AND LOWER(n.source) = um.value)
+			OR (um.kind = 'keyword' AND LOWER(n.title) LIKE '%%' || REPLACE(REPLACE(REPLACE(um.value, '\', '\\'), '%%', '\%%'), '_', '\_') || '%%' ESCAPE '\')
+		)
+	)`, placeholder)
+}
+
+// normalizeMute lowercases the value of author, source and keyword mutes.
+func normalizeMute(mute Mute) (Mute, error) {
+	switch mute.Kind {
+	case MuteTag:
+		return Mute{Kind: MuteTag, TagID: mute.TagID}, nil
+	case MuteAuthor, MuteSource, MuteKeyword:
+		value := strings.ToLower(strings.TrimSpace(mute.Value))
+		if value == "" {
+			return mute, fmt.Errorf("%v mute without a value", mute.Kind)
+		}
+		return Mute{Kind: mute.Kind, Value: value}, nil
+	default:
+		return mute, fmt.Errorf("unknown mute kind %q", mute.Kind)
+	}
+}
+
+// GetMutes returns the user's mutes: tags, sources, keywords and then
+// authors, each ordered alphabetically.
+func (r *SQLRepository) GetMutes(ctx context.Context, userID string) ([]Mute, error) {
+	query := `
+		SELECT 'tag' AS kind, t.id AS tag_id, t.name AS value
+		FROM user_muted_tags um
+		JOIN tags t ON um.tag_id = t.id
+		WHERE um.user_id = $1
+		UNION ALL
+		SELECT kind, 0 AS tag_id, value
+		FROM user_muted_terms
+		WHERE user_id = $1
+		ORDER BY kind DESC, value
+	`
+	var mutes []Mute
+	err := r.db.SelectContext(ctx, &mutes, query, userID)
+	return mutes, err
+}
+
+func (r *SQLRepository) AddMute(ctx context.Context, userID string, mute Mute) error {
+	mute, err := normalizeMute(mute)
+	if err != nil {
+		return err
+	}
+	if mute.Kind == MuteTag {
+		query := `INSERT INTO user_muted_tags (user_id, tag_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+		_, err = r.db.ExecContext(ctx, query, userID, mute.TagID)
+		return err
+	}
+	query := `INSERT INTO user_muted_terms (user_id, kind, value) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`
+	_, err = r.db.ExecContext(ctx, query, userID, mute.Kind, mute.Value)
+	return err
+}
+
+func (r *SQLRepository) RemoveMute(ctx context.Context, userID string, mute Mute) error {
+	mute, err := normalizeMute(mute)
+	if err != nil {
+		return err
+	}
+	if mute.Kind == MuteTag {
+		query := `DELETE FROM user_muted_tags WHERE user_id = $1 AND tag_id = $2`
+		_, err = r.db.ExecContext(ctx, query, userID, mute.TagID)
+		return err
+	}
+	query := `DELETE FROM user_muted_terms WHERE user_id = $1 AND kind = $2 AND value = $3`
+	_, err = r.db.ExecContext(ctx, query, userID, mute.Kind, mute.Value)
+	return err
+}
@@ -0,0 +1,39 @@
+package database
+
+import (
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+)
+
+// Open connects to the database described by dsn. DSNs starting with
+// sqlite: or file: open a SQLite database file, anything else is handed to
+// the PostgreSQL driver.
+func Open(dsn string) (*sqlx.DB, error) {
+	if file, ok := sqliteFile(dsn); ok {
+		params := "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_time_format=sqlite"
+		sep := "?"
+		if strings.Contains(file, "?") {
+			sep = "&"
+		}
+		db, err := sqlx.Open("sqlite", "file:"+file+sep+params)
+		if err != nil {
+			return nil, err
+		}
+		// SQLite allows a single writer; serializing connections avoids
+		// SQLITE_BUSY errors under concurrent requests.
+		db.SetMaxOpenConns(1)
+		return db, nil
+	}
+	return sqlx.Open("postgres", dsn)
+}
+
+func sqliteFile(dsn string) (string, bool) {
+	for _, prefix := range []string{"sqlite://", "sqlite:", "file:"} {
+		if rest, ok := strings.CutPrefix(dsn, prefix); ok {
+			return rest, true
+		}
+	}
+	return "", false
+}
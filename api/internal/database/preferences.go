@@ -0,0 +1,122 @@
+package database
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+type DigestFrequency string
+
+const (
+	DigestOff    DigestFrequency = "off"
+	DigestDaily  DigestFrequency = "daily"
+	DigestWeekly DigestFrequency = "weekly"
+)
+
+// MaxPageSize is the largest default page size a user can choose.
+const MaxPageSize = 100
+
+// Preferences are the settings of a user, stored as a JSON document.
+type Preferences struct {
+	// Timezone is the IANA time zone dates are interpreted in.
+	Timezone string `json:"timezone" validate:"required,timezone"`
+	// Language is a BCP 47 language tag, e.g. en or pt-BR.
+	Language        string          `json:"language" validate:"required,bcp47_language_tag"`
+	DigestFrequency DigestFrequency `json:"digest_frequency" validate:"oneof=off daily weekly"`
+	// PageSize is the default limit of news lists, 0 meaning no limit.
+	PageSize int `json:"page_size" validate:"gte=0,lte=100"`
+	// HideRead leaves the news the user read out of news lists.
+	HideRead bool `json:"hide_read"`
+	// HideDuplicates collapses news lists into stories unless asked
+	// otherwise.
+	HideDuplicates bool `json:"hide_duplicates"`
+}
+
+func DefaultPreferences() Preferences {
+	return Preferences{
+		Timezone:        "UTC",
+		Language:        "en",
+		DigestFrequency: DigestOff,
+	}
+}
+
+var preferencesValidator = func() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		return strings.Split(field.Tag.Get("json"), ",")[0]
+	})
+	return v
+}()
+
+// Validate checks the preferences against their schema, naming every invalid
+// field in the error.
+func (p Preferences) Validate() error {
+	err := preferencesValidator.Struct(p)
+	var fieldErrors validator.ValidationErrors
+	if !errors.As(err, &fieldErrors) {
+		return err
+	}
+	messages := make([]string, 0, len(fieldErrors))
+	for _, fieldError := range fieldErrors {
+		messages = append(messages, fmt.Sprintf("invalid %v %v", fieldError.Field(), describeConstraint(fieldError)))
+	}
+	return errors.New(strings.Join(messages, ", "))
+}
+
+func describeConstraint(fieldError validator.FieldError) string {
+	switch fieldError.Tag() {
+	case "required":
+		return "value, it cannot be empty"
+	case "timezone":
+		return fmt.Sprintf("%q, expected an IANA time zone such as Europe/Warsaw", fieldError.Value())
+	case "bcp47_language_tag":
+		return fmt.Sprintf("%q, expected a language tag such as en or pt-BR", fieldError.Value())
+	case "oneof":
+		return fmt.Sprintf("%q, expected one of %v", fieldError.Value(), fieldError.Param())
+	case "gte", "lte":
+		return fmt.Sprintf("%v, expected a number between 0 and %d", fieldError.Value(), MaxPageSize)
+	default:
+		return fmt.Sprintf("%v", fieldError.Value())
+	}
+}
+
+// Location returns the time zone of the user, UTC if it cannot be loaded.
+func (p Preferences) Location() *time.Location {
+	location, err := time.LoadLocation(p.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+// Scan implements sql.Scanner, filling in the defaults for settings missing
+// from the stored document.
+func (p *Preferences) Scan(src any) error {
+	*p = DefaultPreferences()
+	switch src := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(src, p)
+	case string:
+		return json.Unmarshal([]byte(src), p)
+	default:
+		return fmt.Errorf("cannot scan %T into preferences", src)
+	}
+}
+
+// Value implements driver.Valuer.
+func (p Preferences) Value() (driver.Value, error) {
+	b, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
@@ -5,28 +5,83 @@ import (
 	"database/sql"
 	"fmt"
 	"strings"
+	"time"
 
 	"errors"
 
 	"github.com/jmoiron/sqlx"
 )
 
+// SchemaVersion is the latest migration in db/migrations this build expects
+// to be applied.
+const SchemaVersion = 14
+
 type Repository interface {
+	Ping(ctx context.Context) error
+	GetSchemaVersion(ctx context.Context) (int, error)
+
 	UpsertUser(ctx context.Context, user *User) error
 	GetUserByGoogleID(ctx context.Context, googleID string) (*User, error)
 	GetUserByEmail(ctx context.Context, email string) (*User, error)
+	GetUserByID(ctx context.Context, id string) (*User, error)
+	UpdatePreferences(ctx context.Context, userID string, preferences Preferences) error
+	RequestUserDeletion(ctx context.Context, userID string) error
+	CancelUserDeletion(ctx context.Context, userID string) error
+	GetUsersPendingDeletion(ctx context.Context, requestedBefore time.Time) ([]User, error)
+	PurgeUser(ctx context.Context, userID string) error
 
 	GetAllTags(ctx context.Context) ([]Tag, error)
+	GetTagDetail(ctx context.Context, tagID int) (*TagDetail, error)
+	ResolveTag(ctx context.Context, name string) (*Tag, error)
+	GetTagAliases(ctx context.Context) ([]TagAlias, error)
+	SetTagParent(ctx context.Context, tagID int, parentID *int) error
+	AddTagAlias(ctx context.Context, tagID int, alias string) error
+	RemoveTagAlias(ctx context.Context, alias string) error
 
 	GetNewsByID(ctx context.Context, id int) (*News, error)
+	GetNewsByIDs(ctx context.Context, ids []int) ([]News, error)
 	GetAllNews(ctx context.Context) ([]News, error)
-	GetNewsByTag(ctx context.Context, tagID int) ([]News, error)
+	SearchNews(ctx context.Context, filter NewsFilter) ([]News, error)
+	GetNewsByTag(ctx context.Context, tagID int, includeDescendants bool, viewerID string) ([]News, error)
 	GetTagsForNews(ctx context.Context, newsID int) ([]Tag, error)
+	AddTagsToNews(ctx context.Context, newsID int, tags []TagAssignment) error
 
 	AddFavoriteTag(ctx context.Context, userID string, tagID int) error
 	RemoveFavoriteTag(ctx context.Context, userID string, tagID int) error
 	GetFavoriteTags(ctx context.Context, userID string) ([]Tag, error)
-	GetFavoriteNews(ctx context.Context, userID string) ([]News, error)
+	GetFavoriteNews(ctx context.Context, userID string, includeDescendants bool) ([]News, error)
+
+	GetMutes(ctx context.Context, userID string) ([]Mute, error)
+	AddMute(ctx context.Context, userID string, mute Mute) error
+	RemoveMute(ctx context.Context, userID string, mute Mute) error
+
+	AddInteraction(ctx context.Context, userID string, newsID int, kind InteractionKind) error
+	RemoveInteraction(ctx context.Context, userID string, newsID int, kind InteractionKind) error
+	GetInteractions(ctx context.Context, userID string) ([]Interaction, error)
+	GetInteractionCounts(ctx context.Context, since time.Time) ([]InteractionCount, error)
+
+	GetTagNewsCounts(ctx context.Context) (map[int]int, error)
+	GetTagCooccurrence(ctx context.Context) ([]TagPair, error)
+	GetTagFavoriteCounts(ctx context.Context) (map[int]int, error)
+
+	GetUnfingerprintedNews(ctx context.Context, limit int) ([]News, error)
+	GetFingerprints(ctx context.Context, since, until time.Time) ([]Fingerprint, error)
+	AddFingerprint(ctx context.Context, fingerprint *Fingerprint) error
+	GetStoryMemberships(ctx context.Context, newsIDs []int) (map[int]StoryMembership, error)
+	GetStoryCluster(ctx context.Context, id int) (*StoryCluster, error)
+
+	CreateSavedSearch(ctx context.Context, search *SavedSearch) error
+	GetSavedSearches(ctx context.Context, userID string) ([]SavedSearch, error)
+	GetSavedSearch(ctx context.Context, userID string, id int) (*SavedSearch, error)
+	DeleteSavedSearch(ctx context.Context, userID string, id int) error
+	GetAllSavedSearches(ctx context.Context) ([]SavedSearch, error)
+	AddSearchMatches(ctx context.Context, searchID int, newsIDs []int) error
+	GetSearchMatches(ctx context.Context, searchID int) ([]SearchMatch, error)
+	GetUnsearchedNewsIDs(ctx context.Context, limit int) ([]int, error)
+	MarkNewsSearched(ctx context.Context, newsIDs []int) error
+
+	AddAuditEvent(ctx context.Context, event *AuditEvent) error
+	GetAuditEvents(ctx context.Context, filter AuditFilter) ([]AuditEvent, error)
 }
 
 type SQLRepository struct {
@@ -37,21 +92,38 @@ func NewSQLRepository(db *sqlx.DB) Repository {
 	return &SQLRepository{db: db}
 }
 
+func (r *SQLRepository) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
+
+func (r *SQLRepository) GetSchemaVersion(ctx context.Context) (int, error) {
+	var version int
+	query := `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`
+	err := r.db.GetContext(ctx, &version, query)
+	return version, err
+}
+
 func (r *SQLRepository) UpsertUser(ctx context.Context, user *User) error {
 	query := `
-		INSERT INTO users (google_id, email)
-		VALUES ($1, $2)
+		INSERT INTO users (id, google_id, email)
+		VALUES ($1, $2, $3)
 		ON CONFLICT (google_id) DO UPDATE
 		SET email = EXCLUDED.email
-		RETURNING id, created_at
+		RETURNING id, created_at, preferences, deletion_requested_at
 	`
 
+	id, err := newUUID()
+	if err != nil {
+		return err
+	}
+
 	return r.db.QueryRowxContext(
 		ctx,
 		query,
+		id,
 		user.GoogleID,
 		user.Email,
-	).Scan(&user.ID, &user.CreatedAt)
+	).Scan(&user.ID, &user.CreatedAt, &user.Preferences, &user.DeletionRequestedAt)
 }
 
 func (r *SQLRepository) GetUserByGoogleID(ctx context.Context, googleID string) (*User, error) {
@@ -74,9 +146,25 @@ func (r *SQLRepository) GetUserByEmail(ctx context.Context, email string) (*User
 	return user, err
 }
 
+func (r *SQLRepository) GetUserByID(ctx context.Context, id string) (*User, error) {
+	user := &User{}
+	query := `SELECT * FROM users WHERE id = $1`
+	err := r.db.GetContext(ctx, user, query, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return user, err
+}
+
+func (r *SQLRepository) UpdatePreferences(ctx context.Context, userID string, preferences Preferences) error {
+	query := `UPDATE users SET preferences = $1 WHERE id = $2`
+	_, err := r.db.ExecContext(ctx, query, preferences, userID)
+	return err
+}
+
 func (r *SQLRepository) GetAllTags(ctx context.Context) ([]Tag, error) {
 	var tags []Tag
-	query := `SELECT * FROM tags`
+	query := `SELECT * FROM tags ORDER BY id`
 	err := r.db.SelectContext(ctx, &tags, query)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
@@ -87,6 +175,35 @@ func (r *SQLRepository) GetAllTags(ctx context.Context) ([]Tag, error) {
 	return tags, err
 }
 
+// GetTagDetail returns a tag with the number of news tagged with it, the
+// number of users following it and when it was last used, or nil.
+func (r *SQLRepository) GetTagDetail(ctx context.Context, tagID int) (*TagDetail, error) {
+	detail := &TagDetail{}
+	query := `
+		SELECT t.*,
+			(SELECT COUNT(*) FROM news_tags nt WHERE nt.tag_id = t.id) AS news_count,
+			(SELECT COUNT(*) FROM user_favorite_tags f WHERE f.tag_id = t.id) AS follower_count,
+			latest.created_at AS latest_news_at
+		FROM tags t
+		LEFT JOIN news latest ON latest.id = (
+			SELECT n.id FROM news n
+			JOIN news_tags nt ON nt.news_id = n.id
+			WHERE nt.tag_id = t.id
+			ORDER BY n.created_at DESC, n.id DESC
+			LIMIT 1
+		)
+		WHERE t.id = $1
+	`
+	err := r.db.GetContext(ctx, detail, query, tagID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return detail, nil
+}
+
 func (r *SQLRepository) GetNewsByID(ctx context.Context, id int) (*News, error) {
 	news := &News{}
 	query := `SELECT * FROM news WHERE id = $1`
@@ -107,24 +224,78 @@ func (r *SQLRepository) GetNewsByID(ctx context.Context, id int) (*News, error)
 	return news, nil
 }
 
-func (r *SQLRepository) GetNewsByTag(ctx context.Context, tagID int) ([]News, error) {
+// GetNewsByIDs loads the news with the given ids and their tags in a single
+// query. Ids without a matching row are skipped; the result is ordered like
+// ids.
+func (r *SQLRepository) GetNewsByIDs(ctx context.Context, ids []int) ([]News, error) {
+	if len(ids) == 0 {
+		return []News{}, nil
+	}
+
+	var args queryArgs
+	query := fmt.Sprintf(`
+		SELECT n.*, t.id AS tag_id, t.name AS tag_name, t.slug AS tag_slug
+		FROM news n
+		LEFT JOIN news_tags nt ON n.id = nt.news_id
+		LEFT JOIN tags t ON nt.tag_id = t.id
+		WHERE n.id IN (%v)
+		ORDER BY n.id, t.id
+	`, args.list(ids))
+
+	var newsWithTags []NewsWithTags
+	if err := r.db.SelectContext(ctx, &newsWithTags, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to get news by ids: %w", err)
+	}
+
+	return orderNewsByIDs(combineNewsWithTags(newsWithTags), ids), nil
+}
+
+func orderNewsByIDs(news []News, ids []int) []News {
+	byID := make(map[int]News, len(news))
+	for _, n := range news {
+		byID[n.ID] = n
+	}
+	result := make([]News, 0, len(news))
+	for _, id := range ids {
+		if n, ok := byID[id]; ok {
+			result = append(result, n)
+			delete(byID, id)
+		}
+	}
+	return result
+}
+
+// GetNewsByTag returns the news tagged with the tag, or with any tag below it
+// in the taxonomy when includeDescendants is set. News muted by viewerID, if
+// not empty, are left out.
+func (r *SQLRepository) GetNewsByTag(ctx context.Context, tagID int, includeDescendants bool, viewerID string) ([]News, error) {
+	args := queryArgs{tagID, includeDescendants}
+	muted := ""
+	if viewerID != "" {
+		muted = "AND " + mutedCondition(args.add(viewerID))
+	}
 	query := `
-			WITH filtered_news AS (
+			WITH RECURSIVE tag_tree(id) AS (
+				SELECT CAST($1 AS INTEGER)
+				UNION
+				SELECT t.id FROM tags t JOIN tag_tree tt ON t.parent_id = tt.id WHERE $2
+			),
+			filtered_news AS (
 				SELECT DISTINCT n.id
 				FROM news n
 				JOIN news_tags nt ON n.id = nt.news_id
-				WHERE nt.tag_id = $1
+				WHERE nt.tag_id IN (SELECT id FROM tag_tree) ` + muted + `
 			)
-			SELECT n.*, t.id AS tag_id, t.name AS tag_name
+			SELECT n.*, t.id AS tag_id, t.name AS tag_name, t.slug AS tag_slug
 			FROM news n
 			JOIN news_tags nt ON n.id = nt.news_id
 			JOIN tags t ON nt.tag_id = t.id
 			WHERE n.id IN (SELECT id FROM filtered_news)
-			ORDER BY n.created_at DESC
+			ORDER BY n.created_at DESC, n.id DESC, t.id
     `
 
 	var newsWithTags []NewsWithTags
-	if err := r.db.SelectContext(ctx, &newsWithTags, query, tagID); err != nil {
+	if err := r.db.SelectContext(ctx, &newsWithTags, query, args...); err != nil {
 		return nil, fmt.Errorf("failed to get news by tag: %w", err)
 	}
 
@@ -133,11 +304,11 @@ func (r *SQLRepository) GetNewsByTag(ctx context.Context, tagID int) ([]News, er
 
 func (r *SQLRepository) GetAllNews(ctx context.Context) ([]News, error) {
 	query := `
-		SELECT n.*, t.id AS tag_id, t.name AS tag_name
+		SELECT n.*, t.id AS tag_id, t.name AS tag_name, t.slug AS tag_slug
 		FROM news n
 		LEFT JOIN news_tags nt ON n.id = nt.news_id
 		LEFT JOIN tags t ON nt.tag_id = t.id
-		ORDER BY n.created_at DESC
+		ORDER BY n.created_at DESC, n.id DESC, t.id
 	`
 
 	var newsWithTags []NewsWithTags
@@ -148,22 +319,26 @@ func (r *SQLRepository) GetAllNews(ctx context.Context) ([]News, error) {
 	return combineNewsWithTags(newsWithTags), nil
 }
 
-func (r *SQLRepository) AddTagsToNews(ctx context.Context, newsID int, tagIDs []int) error {
-	if len(tagIDs) == 0 {
+// AddTagsToNews tags a news item. Tags it already has keep the higher of both
+// confidences.
+func (r *SQLRepository) AddTagsToNews(ctx context.Context, newsID int, tags []TagAssignment) error {
+	if len(tags) == 0 {
 		return nil
 	}
 
-	query := `INSERT INTO news_tags (news_id, tag_id) VALUES `
-	valueStrings := make([]string, 0, len(tagIDs))
-	valueArgs := make([]any, 0, len(tagIDs)*2)
+	query := `INSERT INTO news_tags (news_id, tag_id, confidence) VALUES `
+	valueStrings := make([]string, 0, len(tags))
+	valueArgs := make([]any, 0, len(tags)*3)
 
-	for i, tagID := range tagIDs {
-		valueStrings = append(valueStrings, fmt.Sprintf("($%d, $%d)", i*2+1, i*2+2))
-		valueArgs = append(valueArgs, newsID, tagID)
+	for i, tag := range tags {
+		valueStrings = append(valueStrings, fmt.Sprintf("($%d, $%d, $%d)", i*3+1, i*3+2, i*3+3))
+		valueArgs = append(valueArgs, newsID, tag.TagID, tag.Confidence)
 	}
 
 	query += strings.Join(valueStrings, ",")
-	query += " ON CONFLICT DO NOTHING"
+	query += ` ON CONFLICT (news_id, tag_id) DO UPDATE
+		SET confidence = CASE WHEN EXCLUDED.confidence > news_tags.confidence
+			THEN EXCLUDED.confidence ELSE news_tags.confidence END`
 
 	_, err := r.db.ExecContext(ctx, query, valueArgs...)
 	return err
@@ -175,6 +350,7 @@ func (r *SQLRepository) GetTagsForNews(ctx context.Context, newsID int) ([]Tag,
 		FROM tags t
 		JOIN news_tags nt ON t.id = nt.tag_id
 		WHERE nt.news_id = $1
+		ORDER BY t.id
 	`
 	var tags []Tag
 	err := r.db.SelectContext(ctx, &tags, query, newsID)
@@ -203,40 +379,53 @@ func (r *SQLRepository) GetFavoriteTags(ctx context.Context, userID string) ([]T
 		FROM tags t
 		JOIN user_favorite_tags uft ON t.id = uft.tag_id
 		WHERE uft.user_id = $1
+		ORDER BY t.id
 	`
 	var tags []Tag
 	err := r.db.SelectContext(ctx, &tags, query, userID)
 	return tags, err
 }
 
-func (r *SQLRepository) GetFavoriteNews(ctx context.Context, userID string) ([]News, error) {
+// GetFavoriteNews returns the news tagged with tags the user follows, or with
+// tags below them in the taxonomy when includeDescendants is set, except
+// those the user muted. Every news item only carries the followed tags.
+func (r *SQLRepository) GetFavoriteNews(ctx context.Context, userID string, includeDescendants bool) ([]News, error) {
 	query := `
-		SELECT DISTINCT n.*, t.id AS tag_id, t.name AS tag_name
+		WITH RECURSIVE favorite_tree(id) AS (
+			SELECT tag_id FROM user_favorite_tags WHERE user_id = $1
+			UNION
+			SELECT t.id FROM tags t JOIN favorite_tree ft ON t.parent_id = ft.id WHERE $2
+		)
+		SELECT DISTINCT n.*, t.id AS tag_id, t.name AS tag_name, t.slug AS tag_slug
 		FROM news n
 		JOIN news_tags nt ON n.id = nt.news_id
-		JOIN user_favorite_tags uft ON nt.tag_id = uft.tag_id
 		JOIN tags t ON nt.tag_id = t.id
-		WHERE uft.user_id = $1
-		ORDER BY n.created_at DESC
+		WHERE nt.tag_id IN (SELECT id FROM favorite_tree) AND ` + mutedCondition("$1") + `
+		ORDER BY n.created_at DESC, n.id DESC, t.id
 	`
 
 	var newsWithTags []NewsWithTags
-	if err := r.db.SelectContext(ctx, &newsWithTags, query, userID); err != nil {
+	if err := r.db.SelectContext(ctx, &newsWithTags, query, userID, includeDescendants); err != nil {
 		return nil, err
 	}
 
 	return combineNewsWithTags(newsWithTags), nil
 }
 
+// combineNewsWithTags folds joined news/tag rows into news with their tags,
+// keeping the order in which news first appear in the rows.
 func combineNewsWithTags(newsWithTags []NewsWithTags) []News {
 	newsMap := make(map[int]*News)
+	order := make([]int, 0)
 	for _, nwt := range newsWithTags {
 		if _, exists := newsMap[nwt.ID]; !exists {
+			order = append(order, nwt.ID)
 			newsMap[nwt.ID] = &News{
 				ID:        nwt.ID,
 				Title:     nwt.Title,
 				Content:   nwt.Content,
 				Author:    nwt.Author,
+				Source:    nwt.Source,
 				CreatedAt: nwt.CreatedAt,
 				Tags:      []Tag{},
 			}
@@ -246,13 +435,14 @@ func combineNewsWithTags(newsWithTags []NewsWithTags) []News {
 			newsMap[nwt.ID].Tags = append(newsMap[nwt.ID].Tags, Tag{
 				ID:   *nwt.TagID,
 				Name: *nwt.TagName,
+				Slug: *nwt.TagSlug,
 			})
 		}
 	}
 
-	result := make([]News, 0, len(newsMap))
-	for _, n := range newsMap {
-		result = append(result, *n)
+	result := make([]News, 0, len(order))
+	for _, id := range order {
+		result = append(result, *newsMap[id])
 	}
 	return result
 }
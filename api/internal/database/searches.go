@@ -0,0 +1,206 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Filter returns the filter running the search at the given time, leaving out
+// the news its owner muted.
+func (s SavedSearch) Filter(now time.Time) NewsFilter {
+	filter := NewsFilter{
+		TagIDs:       s.TagIDs,
+		MatchAllTags: s.MatchAllTags,
+		Query:        s.Query,
+		ViewerID:     s.UserID,
+	}
+	if s.WindowDays > 0 {
+		since := now.AddDate(0, 0, -s.WindowDays)
+		filter.Since = &since
+	}
+	return filter
+}
+
+// CreateSavedSearch stores the search, filling in its ID and creation time.
+// Only news ingested from then on are matched against it.
+func (r *SQLRepository) CreateSavedSearch(ctx context.Context, search *SavedSearch) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO saved_searches (user_id, name, query, match_all_tags, window_days, last_news_id)
+		VALUES ($1, $2, $3, $4, $5, (SELECT COALESCE(MAX(id), 0) FROM news))
+		RETURNING id, last_news_id, created_at
+	`
+	row := tx.QueryRowxContext(ctx, query, search.UserID, search.Name, search.Query, search.MatchAllTags, search.WindowDays)
+	if err := row.Scan(&search.ID, &search.LastNewsID, &search.CreatedAt); err != nil {
+		return fmt.Errorf("failed to create saved search: %w", err)
+	}
+
+	if len(search.TagIDs) > 0 {
+		var args queryArgs
+		values := make([]string, 0, len(search.TagIDs))
+		for _, tagID := range search.TagIDs {
+			values = append(values, fmt.Sprintf("(%v, %v)", args.add(search.ID), args.add(tagID)))
+		}
+		query := `INSERT INTO saved_search_tags (search_id, tag_id) VALUES ` + strings.Join(values, ", ")
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return fmt.Errorf("failed to add saved search tags: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// GetSavedSearches returns the user's searches, oldest first.
+func (r *SQLRepository) GetSavedSearches(ctx context.Context, userID string) ([]SavedSearch, error) {
+	query := `SELECT * FROM saved_searches WHERE user_id = $1 ORDER BY id`
+	var searches []SavedSearch
+	if err := r.db.SelectContext(ctx, &searches, query, userID); err != nil {
+		return nil, err
+	}
+	return searches, r.loadSavedSearchTags(ctx, searches)
+}
+
+// GetSavedSearch returns one of the user's searches, or nil.
+func (r *SQLRepository) GetSavedSearch(ctx context.Context, userID string, id int) (*SavedSearch, error) {
+	search := SavedSearch{}
+	query := `SELECT * FROM saved_searches WHERE user_id = $1 AND id = $2`
+	err := r.db.GetContext(ctx, &search, query, userID, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	searches := []SavedSearch{search}
+	if err := r.loadSavedSearchTags(ctx, searches); err != nil {
+		return nil, err
+	}
+	return &searches[0], nil
+}
+
+func (r *SQLRepository) DeleteSavedSearch(ctx context.Context, userID string, id int) error {
+	query := `DELETE FROM saved_searches WHERE user_id = $1 AND id = $2`
+	_, err := r.db.ExecContext(ctx, query, userID, id)
+	return err
+}
+
+// GetAllSavedSearches returns the searches of every user, oldest first.
+func (r *SQLRepository) GetAllSavedSearches(ctx context.Context) ([]SavedSearch, error) {
+	query := `SELECT * FROM saved_searches ORDER BY id`
+	var searches []SavedSearch
+	if err := r.db.SelectContext(ctx, &searches, query); err != nil {
+		return nil, err
+	}
+	return searches, r.loadSavedSearchTags(ctx, searches)
+}
+
+// AddSearchMatches records the news matching a search, ignoring those
+// already recorded.
+func (r *SQLRepository) AddSearchMatches(ctx context.Context, searchID int, newsIDs []int) error {
+	if len(newsIDs) == 0 {
+		return nil
+	}
+	var args queryArgs
+	values := make([]string, 0, len(newsIDs))
+	for _, newsID := range newsIDs {
+		values = append(values, fmt.Sprintf("(%v, %v)", args.add(searchID), args.add(newsID)))
+	}
+	query := `INSERT INTO saved_search_matches (search_id, news_id) VALUES ` + strings.Join(values, ", ") + `
+		ON CONFLICT DO NOTHING`
+	if _, err := r.db.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to add search matches: %w", err)
+	}
+	return nil
+}
+
+// GetSearchMatches returns the matches of a search, most recent first.
+func (r *SQLRepository) GetSearchMatches(ctx context.Context, searchID int) ([]SearchMatch, error) {
+	query := `
+		SELECT * FROM saved_search_matches
+		WHERE search_id = $1
+		ORDER BY matched_at DESC, news_id DESC
+	`
+	var matches []SearchMatch
+	err := r.db.SelectContext(ctx, &matches, query, searchID)
+	return matches, err
+}
+
+// GetUnsearchedNewsIDs returns the ids of up to limit news not yet checked
+// against the saved searches, lowest first.
+func (r *SQLRepository) GetUnsearchedNewsIDs(ctx context.Context, limit int) ([]int, error) {
+	query := `
+		SELECT n.id
+		FROM news n
+		WHERE NOT EXISTS (SELECT 1 FROM searched_news s WHERE s.news_id = n.id)
+		ORDER BY n.id
+		LIMIT $1
+	`
+	ids := make([]int, 0)
+	if err := r.db.SelectContext(ctx, &ids, query, limit); err != nil {
+		return nil, fmt.Errorf("failed to get unsearched news: %w", err)
+	}
+	return ids, nil
+}
+
+// MarkNewsSearched records that the news were checked against the saved
+// searches.
+func (r *SQLRepository) MarkNewsSearched(ctx context.Context, newsIDs []int) error {
+	if len(newsIDs) == 0 {
+		return nil
+	}
+	var args queryArgs
+	values := make([]string, 0, len(newsIDs))
+	for _, newsID := range newsIDs {
+		values = append(values, fmt.Sprintf("(%v)", args.add(newsID)))
+	}
+	query := `INSERT INTO searched_news (news_id) VALUES ` + strings.Join(values, ", ") + ` ON CONFLICT DO NOTHING`
+	if _, err := r.db.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to mark news searched: %w", err)
+	}
+	return nil
+}
+
+// loadSavedSearchTags fills in the tag filters of the searches.
+func (r *SQLRepository) loadSavedSearchTags(ctx context.Context, searches []SavedSearch) error {
+	if len(searches) == 0 {
+		return nil
+	}
+	ids := make([]int, 0, len(searches))
+	for _, search := range searches {
+		ids = append(ids, search.ID)
+	}
+
+	var args queryArgs
+	query := fmt.Sprintf(`
+		SELECT search_id, tag_id FROM saved_search_tags
+		WHERE search_id IN (%v)
+		ORDER BY search_id, tag_id
+	`, args.list(ids))
+	var rows []struct {
+		SearchID int `db:"search_id"`
+		TagID    int `db:"tag_id"`
+	}
+	if err := r.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return fmt.Errorf("failed to get saved search tags: %w", err)
+	}
+
+	tags := make(map[int][]int)
+	for _, row := range rows {
+		tags[row.SearchID] = append(tags[row.SearchID], row.TagID)
+	}
+	for i := range searches {
+		searches[i].TagIDs = tags[searches[i].ID]
+		if searches[i].TagIDs == nil {
+			searches[i].TagIDs = []int{}
+		}
+	}
+	return nil
+}
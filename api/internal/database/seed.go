@@ -0,0 +1,73 @@
+package database
+
+import (
+	"context"
+	"time"
+)
+
+// SeedMemoryRepository fills repo with the same sample data as db/fill_db.sql.
+func SeedMemoryRepository(ctx context.Context, repo *MemoryRepository) error {
+	tags := []struct{ name, description string }{
+		{"golang", "The Go programming language"},
+		{"python", "The Python programming language"},
+		{"javascript", "JavaScript and its ecosystem"},
+		{"rust", "The Rust programming language"},
+		{"docker", "Containers and Docker tooling"},
+		{"kubernetes", "Container orchestration with Kubernetes"},
+		{"webdev", "Building for the web"},
+		{"datascience", "Data analysis and visualisation"},
+		{"machinelearning", "Machine learning models and tools"},
+		{"algorithms", "Algorithms and data structures"},
+		{"devops", "Deploying and operating software"},
+	}
+	tagIDs := make(map[string]int, len(tags))
+	for _, t := range tags {
+		tag, err := repo.CreateTag(ctx, t.name, t.description)
+		if err != nil {
+			return err
+		}
+		tagIDs[t.name] = tag.ID
+	}
+
+	parents := map[string]string{"docker": "devops", "kubernetes": "devops", "machinelearning": "datascience"}
+	for name, parent := range parents {
+		parentID := tagIDs[parent]
+		if err := repo.SetTagParent(ctx, tagIDs[name], &parentID); err != nil {
+			return err
+		}
+	}
+	aliases := map[string]string{"go": "golang", "js": "javascript", "k8s": "kubernetes", "ml": "machinelearning"}
+	for alias, name := range aliases {
+		if err := repo.AddTagAlias(ctx, tagIDs[name], alias); err != nil {
+			return err
+		}
+	}
+
+	day := 24 * time.Hour
+	now := time.Now().UTC()
+	seed := []struct {
+		news News
+		tags []string
+	}{
+		{News{Title: "Go 1.21 Released", Content: "The latest version of Go introduces new features including...", Author: "Gopher", Source: "The Go Blog", CreatedAt: now.Add(-2 * day)}, []string{"golang", "webdev"}},
+		{News{Title: "Python 3.12 Performance Improvements", Content: "Significant speed boosts reported in the new Python release...", Author: "PyDev", Source: "Python Insider", CreatedAt: now.Add(-5 * day)}, []string{"python", "datascience"}},
+		{News{Title: "Rust for Web Development", Content: "How Rust is becoming a viable alternative for backend web services...", Author: "Ferris", Source: "This Week in Rust", CreatedAt: now.Add(-7 * day)}, []string{"rust", "webdev"}},
+		{News{Title: "Docker Best Practices 2023", Content: "Updated guidelines for containerizing your applications...", Author: "Container Expert", Source: "Docker Blog", CreatedAt: now.Add(-3 * day)}, []string{"docker", "kubernetes"}},
+		{News{Title: "Machine Learning with Go", Content: "Exploring ML libraries available for the Go programming language...", Author: "AI Researcher", Source: "The Go Blog", CreatedAt: now.Add(-10 * day)}, []string{"golang", "machinelearning", "algorithms"}},
+	}
+
+	for _, s := range seed {
+		news := s.news
+		if err := repo.CreateNews(ctx, &news); err != nil {
+			return err
+		}
+		tags := make([]TagAssignment, 0, len(s.tags))
+		for _, name := range s.tags {
+			tags = append(tags, TagAssignment{TagID: tagIDs[name], Confidence: 1})
+		}
+		if err := repo.AddTagsToNews(ctx, news.ID, tags); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package database
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	_ "modernc.org/sqlite"
+)
+
+//go:embed migrations/sqlite/*.sql
+var sqliteMigrations embed.FS
+
+// SQLiteRepository is the Repository for single-node deployments. It shares
+// every query with SQLRepository, which only uses SQL both databases
+// understand; a method needing a different dialect would be overridden here.
+type SQLiteRepository struct {
+	*SQLRepository
+}
+
+func NewSQLiteRepository(db *sqlx.DB) Repository {
+	return &SQLiteRepository{SQLRepository: &SQLRepository{db: db}}
+}
+
+// MigrateSQLite applies the embedded SQLite migrations newer than the current
+// schema version, each in its own transaction.
+func MigrateSQLite(ctx context.Context, db *sqlx.DB) error {
+	var current int
+	var exists int
+	query := `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'`
+	if err := db.GetContext(ctx, &exists, query); err != nil {
+		return err
+	}
+	if exists > 0 {
+		if err := db.GetContext(ctx, &current, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`); err != nil {
+			return err
+		}
+	}
+
+	files, err := fs.Glob(sqliteMigrations, "migrations/sqlite/*.sql")
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		version, err := strconv.Atoi(strings.SplitN(path.Base(file), "_", 2)[0])
+		if err != nil {
+			return fmt.Errorf("invalid migration file name %v: %w", file, err)
+		}
+		if version <= current {
+			continue
+		}
+
+		migration, err := sqliteMigrations.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		tx, err := db.BeginTxx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, string(migration)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("applying migration %v failed: %w", file, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestSQLiteRepository(t *testing.T) {
+	testRepository(t, func(t *testing.T) (Repository, fixtures) {
+		db, err := Open("sqlite://" + filepath.Join(t.TempDir(), "news.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+		if err := MigrateSQLite(context.Background(), db); err != nil {
+			t.Fatal(err)
+		}
+		return NewSQLiteRepository(db), sqlFixtures{db: db}
+	})
+}
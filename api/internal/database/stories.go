@@ -0,0 +1,126 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// GetUnfingerprintedNews returns up to limit news not yet assigned to a story
+// cluster, oldest first, without their tags.
+func (r *SQLRepository) GetUnfingerprintedNews(ctx context.Context, limit int) ([]News, error) {
+	query := `
+		SELECT n.*
+		FROM news n
+		WHERE NOT EXISTS (SELECT 1 FROM news_fingerprints f WHERE f.news_id = n.id)
+		ORDER BY n.created_at, n.id
+		LIMIT $1
+	`
+	news := make([]News, 0)
+	if err := r.db.SelectContext(ctx, &news, query, limit); err != nil {
+		return nil, fmt.Errorf("failed to get unfingerprinted news: %w", err)
+	}
+	return news, nil
+}
+
+// GetFingerprints returns the fingerprints of news created in [since, until),
+// oldest first.
+func (r *SQLRepository) GetFingerprints(ctx context.Context, since, until time.Time) ([]Fingerprint, error) {
+	query := `
+		SELECT f.news_id, f.simhash, f.cluster_id, n.created_at
+		FROM news_fingerprints f
+		JOIN news n ON n.id = f.news_id
+		WHERE n.created_at >= $1 AND n.created_at < $2
+		ORDER BY n.created_at, f.news_id
+	`
+	var fingerprints []Fingerprint
+	err := r.db.SelectContext(ctx, &fingerprints, query, since.UTC(), until.UTC())
+	return fingerprints, err
+}
+
+// AddFingerprint stores the fingerprint of a news item. A zero ClusterID
+// starts a new story cluster represented by the news item, and is set to it.
+func (r *SQLRepository) AddFingerprint(ctx context.Context, fingerprint *Fingerprint) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	clusterID := fingerprint.ClusterID
+	if clusterID == 0 {
+		query := `INSERT INTO story_clusters (representative_id) VALUES ($1) RETURNING id`
+		if err := tx.GetContext(ctx, &clusterID, query, fingerprint.NewsID); err != nil {
+			return fmt.Errorf("failed to create story cluster: %w", err)
+		}
+	}
+
+	query := `INSERT INTO news_fingerprints (news_id, simhash, cluster_id) VALUES ($1, $2, $3)`
+	if _, err := tx.ExecContext(ctx, query, fingerprint.NewsID, fingerprint.SimHash, clusterID); err != nil {
+		return fmt.Errorf("failed to add fingerprint: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	fingerprint.ClusterID = clusterID
+	return nil
+}
+
+// GetStoryMemberships returns the story clusters of the given news, keyed by
+// news id. News not clustered yet are missing from the map.
+func (r *SQLRepository) GetStoryMemberships(ctx context.Context, newsIDs []int) (map[int]StoryMembership, error) {
+	memberships := make(map[int]StoryMembership)
+	if len(newsIDs) == 0 {
+		return memberships, nil
+	}
+
+	var args queryArgs
+	query := fmt.Sprintf(`
+		SELECT f.news_id, f.cluster_id, c.representative_id,
+			(SELECT COUNT(*) FROM news_fingerprints m WHERE m.cluster_id = f.cluster_id) AS size
+		FROM news_fingerprints f
+		JOIN story_clusters c ON c.id = f.cluster_id
+		WHERE f.news_id IN (%v)
+	`, args.list(newsIDs))
+
+	var rows []StoryMembership
+	if err := r.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to get story memberships: %w", err)
+	}
+	for _, row := range rows {
+		memberships[row.NewsID] = row
+	}
+	return memberships, nil
+}
+
+// GetStoryCluster returns the cluster with its news, oldest first, or nil when
+// it does not exist.
+func (r *SQLRepository) GetStoryCluster(ctx context.Context, id int) (*StoryCluster, error) {
+	cluster := &StoryCluster{}
+	query := `SELECT id, representative_id FROM story_clusters WHERE id = $1`
+	err := r.db.GetContext(ctx, cluster, query, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	query = `
+		SELECT n.*, t.id AS tag_id, t.name AS tag_name, t.slug AS tag_slug
+		FROM news n
+		JOIN news_fingerprints f ON f.news_id = n.id
+		LEFT JOIN news_tags nt ON n.id = nt.news_id
+		LEFT JOIN tags t ON nt.tag_id = t.id
+		WHERE f.cluster_id = $1
+		ORDER BY n.created_at, n.id, t.id
+	`
+	var newsWithTags []NewsWithTags
+	if err := r.db.SelectContext(ctx, &newsWithTags, query, id); err != nil {
+		return nil, fmt.Errorf("failed to get story cluster news: %w", err)
+	}
+	cluster.News = combineNewsWithTags(newsWithTags)
+	return cluster, nil
+}
@@ -0,0 +1,184 @@
+package database
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"strings"
+)
+
+var (
+	ErrTagNotFound = errors.New("tag not found")
+	ErrTagCycle    = errors.New("tag cannot be its own ancestor")
+	ErrAliasTaken  = errors.New("alias is already a tag name or an alias of another tag")
+)
+
+// NewTagTree nests the tags under their parents, ordered by id. Tags whose
+// parent is missing become roots.
+func NewTagTree(tags []Tag, aliases []TagAlias) []TagNode {
+	byID := make(map[int]Tag, len(tags))
+	for _, tag := range tags {
+		byID[tag.ID] = tag
+	}
+	children := make(map[int][]Tag)
+	var roots []Tag
+	for _, tag := range tags {
+		if tag.ParentID != nil {
+			if _, ok := byID[*tag.ParentID]; ok {
+				children[*tag.ParentID] = append(children[*tag.ParentID], tag)
+				continue
+			}
+		}
+		roots = append(roots, tag)
+	}
+	aliasesByTag := make(map[int][]string)
+	for _, alias := range aliases {
+		aliasesByTag[alias.TagID] = append(aliasesByTag[alias.TagID], alias.Alias)
+	}
+
+	var build func(tags []Tag) []TagNode
+	build = func(tags []Tag) []TagNode {
+		slices.SortFunc(tags, func(a, b Tag) int { return cmp.Compare(a.ID, b.ID) })
+		nodes := make([]TagNode, 0, len(tags))
+		for _, tag := range tags {
+			tagAliases := aliasesByTag[tag.ID]
+			if tagAliases == nil {
+				tagAliases = []string{}
+			}
+			slices.Sort(tagAliases)
+			nodes = append(nodes, TagNode{
+				ID:       tag.ID,
+				Name:     tag.Name,
+				Aliases:  tagAliases,
+				Children: build(children[tag.ID]),
+			})
+		}
+		return nodes
+	}
+	return build(roots)
+}
+
+// checkTagParent validates making parentID the parent of tagID.
+func checkTagParent(tags []Tag, tagID int, parentID *int) error {
+	parents := make(map[int]*int, len(tags))
+	for _, tag := range tags {
+		parents[tag.ID] = tag.ParentID
+	}
+	if _, ok := parents[tagID]; !ok {
+		return ErrTagNotFound
+	}
+	if parentID == nil {
+		return nil
+	}
+	if _, ok := parents[*parentID]; !ok {
+		return ErrTagNotFound
+	}
+	for id := parentID; id != nil; id = parents[*id] {
+		if *id == tagID {
+			return ErrTagCycle
+		}
+	}
+	return nil
+}
+
+func normalizeAlias(alias string) string {
+	return strings.ToLower(strings.TrimSpace(alias))
+}
+
+// Slugify derives the slug of a tag from its name, e.g. "Machine Learning"
+// becomes machine-learning.
+func Slugify(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	return b.String()
+}
+
+// ResolveTag finds a tag by slug, name or alias, case-insensitively, or
+// returns nil.
+func (r *SQLRepository) ResolveTag(ctx context.Context, name string) (*Tag, error) {
+	tag := &Tag{}
+	query := `
+		SELECT t.* FROM tags t
+		WHERE t.slug = $1
+			OR LOWER(t.name) = $1
+			OR t.id IN (SELECT a.tag_id FROM tag_aliases a WHERE a.alias = $1)
+		ORDER BY t.slug = $1 DESC, LOWER(t.name) = $1 DESC, t.id
+		LIMIT 1
+	`
+	err := r.db.GetContext(ctx, tag, query, normalizeAlias(name))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+func (r *SQLRepository) GetTagAliases(ctx context.Context) ([]TagAlias, error) {
+	query := `SELECT * FROM tag_aliases ORDER BY tag_id, alias`
+	var aliases []TagAlias
+	err := r.db.SelectContext(ctx, &aliases, query)
+	return aliases, err
+}
+
+// SetTagParent moves a tag under another one, or to the top of the taxonomy
+// when parentID is nil.
+func (r *SQLRepository) SetTagParent(ctx context.Context, tagID int, parentID *int) error {
+	tags, err := r.GetAllTags(ctx)
+	if err != nil {
+		return err
+	}
+	if err := checkTagParent(tags, tagID, parentID); err != nil {
+		return err
+	}
+
+	query := `UPDATE tags SET parent_id = $1 WHERE id = $2`
+	_, err = r.db.ExecContext(ctx, query, parentID, tagID)
+	return err
+}
+
+// AddTagAlias adds an alias to a tag. Adding an alias the tag already has is
+// a no-op.
+func (r *SQLRepository) AddTagAlias(ctx context.Context, tagID int, alias string) error {
+	alias = normalizeAlias(alias)
+	existing, err := r.ResolveTag(ctx, alias)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		if existing.ID == tagID && !strings.EqualFold(existing.Name, alias) && existing.Slug != alias {
+			return nil
+		}
+		return ErrAliasTaken
+	}
+
+	query := `INSERT INTO tag_aliases (alias, tag_id) SELECT $1, id FROM tags WHERE id = $2`
+	result, err := r.db.ExecContext(ctx, query, alias, tagID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrTagNotFound
+	}
+	return nil
+}
+
+func (r *SQLRepository) RemoveTagAlias(ctx context.Context, alias string) error {
+	query := `DELETE FROM tag_aliases WHERE alias = $1`
+	_, err := r.db.ExecContext(ctx, query, normalizeAlias(alias))
+	return err
+}
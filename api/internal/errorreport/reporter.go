@@ -0,0 +1,28 @@
+// Package errorreport forwards unexpected errors, such as recovered panics,
+// to an external error tracker.
+package errorreport
+
+import (
+	"context"
+	"time"
+)
+
+// Report describes an error and the request it happened in.
+type Report struct {
+	Time    time.Time
+	Message string
+	// Stack is the goroutine stack trace as formatted by runtime/debug.Stack.
+	Stack []byte
+
+	RequestID string
+	Method    string
+	URL       string
+	Route     string
+	UserID    string
+}
+
+// Reporter is a sink for error reports. Report must not block the caller
+// for long, as it is called while serving requests.
+type Reporter interface {
+	Report(ctx context.Context, report Report)
+}
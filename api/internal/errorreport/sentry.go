@@ -0,0 +1,222 @@
+package errorreport
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	sentryQueueSize   = 64
+	sentrySendTimeout = 10 * time.Second
+)
+
+// SentryReporter sends reports as events to the store endpoint of a Sentry
+// compatible server, e.g. Sentry or GlitchTip. Reports are queued and sent by
+// Run; they are dropped while the queue is full.
+type SentryReporter struct {
+	endpoint string
+	auth     string
+	client   *http.Client
+	queue    chan Report
+	server   string
+}
+
+// NewSentryReporter configures a reporter from a DSN of the form
+// https://<key>@<host>/<project id>.
+func NewSentryReporter(dsn string) (*SentryReporter, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid error reporting DSN: %w", err)
+	}
+	project := path.Base(u.Path)
+	if u.User == nil || u.User.Username() == "" || u.Host == "" || project == "" || project == "/" || project == "." {
+		return nil, fmt.Errorf("invalid error reporting DSN, expected https://<key>@<host>/<project id>")
+	}
+
+	endpoint := url.URL{Scheme: u.Scheme, Host: u.Host, Path: path.Join(path.Dir(u.Path), "api", project, "store") + "/"}
+	server, _ := os.Hostname()
+	return &SentryReporter{
+		endpoint: endpoint.String(),
+		auth:     fmt.Sprintf("Sentry sentry_version=7, sentry_client=news/1.0, sentry_key=%v", u.User.Username()),
+		client:   &http.Client{Timeout: sentrySendTimeout},
+		queue:    make(chan Report, sentryQueueSize),
+		server:   server,
+	}, nil
+}
+
+func (s *SentryReporter) Report(ctx context.Context, report Report) {
+	select {
+	case s.queue <- report:
+	default:
+		zerolog.Ctx(ctx).Warn().Msg("error report queue is full, dropping report")
+	}
+}
+
+// Run sends queued reports until ctx is cancelled. Failures are logged and
+// the report is dropped.
+func (s *SentryReporter) Run(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case report := <-s.queue:
+			if err := s.send(ctx, report); err != nil && ctx.Err() == nil {
+				log.Error().Err(err).Str("request_id", report.RequestID).Msg("sending error report failed")
+			}
+		}
+	}
+}
+
+func (s *SentryReporter) send(ctx context.Context, report Report) error {
+	body, err := json.Marshal(s.event(report))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", s.auth)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("error tracker answered %v", resp.Status)
+	}
+	return nil
+}
+
+type sentryEvent struct {
+	EventID    string            `json:"event_id"`
+	Timestamp  string            `json:"timestamp"`
+	Platform   string            `json:"platform"`
+	Level      string            `json:"level"`
+	Logger     string            `json:"logger"`
+	ServerName string            `json:"server_name,omitempty"`
+	Exception  sentryExceptions  `json:"exception"`
+	Tags       map[string]string `json:"tags,omitempty"`
+	Request    *sentryRequest    `json:"request,omitempty"`
+	User       *sentryUser       `json:"user,omitempty"`
+}
+
+type sentryExceptions struct {
+	Values []sentryException `json:"values"`
+}
+
+type sentryException struct {
+	Type       string            `json:"type"`
+	Value      string            `json:"value"`
+	Stacktrace *sentryStacktrace `json:"stacktrace,omitempty"`
+}
+
+type sentryStacktrace struct {
+	Frames []sentryFrame `json:"frames"`
+}
+
+type sentryFrame struct {
+	Function string `json:"function"`
+	AbsPath  string `json:"abs_path"`
+	Filename string `json:"filename"`
+	Lineno   int    `json:"lineno"`
+}
+
+type sentryRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+}
+
+type sentryUser struct {
+	ID string `json:"id"`
+}
+
+func (s *SentryReporter) event(report Report) sentryEvent {
+	id := make([]byte, 16)
+	rand.Read(id)
+
+	event := sentryEvent{
+		EventID:    hex.EncodeToString(id),
+		Timestamp:  report.Time.UTC().Format(time.RFC3339Nano),
+		Platform:   "go",
+		Level:      "error",
+		Logger:     "news",
+		ServerName: s.server,
+		Exception: sentryExceptions{Values: []sentryException{{
+			Type:  "panic",
+			Value: report.Message,
+		}}},
+		Tags: map[string]string{},
+	}
+	if frames := parseStack(report.Stack); len(frames) > 0 {
+		event.Exception.Values[0].Stacktrace = &sentryStacktrace{Frames: frames}
+	}
+	if report.RequestID != "" {
+		event.Tags["request_id"] = report.RequestID
+	}
+	if report.Route != "" {
+		event.Tags["route"] = report.Route
+	}
+	if report.Method != "" || report.URL != "" {
+		event.Request = &sentryRequest{Method: report.Method, URL: report.URL}
+	}
+	if report.UserID != "" {
+		event.User = &sentryUser{ID: report.UserID}
+	}
+	return event
+}
+
+// stackLocation matches the file line of a frame in a Go stack trace, e.g.
+// "\t/src/news/api/handler/news.go:42 +0x1d".
+var stackLocation = regexp.MustCompile(`^\t(.+):(\d+)(?: \+0x[0-9a-f]+)?$`)
+
+// parseStack turns a stack trace from runtime/debug.Stack taken while
+// recovering a panic into frames, the outermost call first as Sentry expects.
+func parseStack(stack []byte) []sentryFrame {
+	lines := strings.Split(string(stack), "\n")
+	var frames []sentryFrame
+	for i := 1; i+1 < len(lines); i++ {
+		match := stackLocation.FindStringSubmatch(lines[i+1])
+		if match == nil || strings.HasPrefix(lines[i], "\t") {
+			continue
+		}
+		function := lines[i]
+		if creator, ok := strings.CutPrefix(function, "created by "); ok {
+			function, _, _ = strings.Cut(creator, " in goroutine ")
+		} else if open := strings.LastIndex(function, "("); open > 0 {
+			function = function[:open]
+		}
+		lineno, _ := strconv.Atoi(match[2])
+		frames = append(frames, sentryFrame{
+			Function: function,
+			AbsPath:  match[1],
+			Filename: path.Base(match[1]),
+			Lineno:   lineno,
+		})
+		i++
+	}
+	// drop the frames of the code recovering the panic, up to the call to panic
+	if at := slices.IndexFunc(frames, func(f sentryFrame) bool { return f.Function == "panic" }); at >= 0 {
+		frames = frames[at+1:]
+	}
+	slices.Reverse(frames)
+	return frames
+}
@@ -1,8 +1,16 @@
 package news
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
 	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq"
 	"github.com/rs/zerolog/log"
 	"github.com/sunba23/news/config"
 	"github.com/sunba23/news/internal/database"
@@ -11,31 +19,179 @@ import (
 type App interface {
 	Config() *config.Config
 	Repository() *database.Repository
+	Draining() bool
+	StartDraining()
 }
 
 type Application struct {
-	config     *config.Config
+	config     atomic.Pointer[config.Config]
 	repository *database.Repository
+	draining   atomic.Bool
+	lifecycle  lifecycle
 }
 
+// Config returns the current configuration. Callers should not keep it
+// around, as reloading the configuration replaces it.
 func (app *Application) Config() *config.Config {
-	return app.config
+	return app.config.Load()
+}
+
+// SetConfig replaces the configuration, e.g. after reloading it.
+func (app *Application) SetConfig(conf *config.Config) {
+	app.config.Store(conf)
 }
 
 func (app *Application) Repository() *database.Repository {
 	return app.repository
 }
 
+// Draining reports whether the application is shutting down and should no
+// longer receive new traffic.
+func (app *Application) Draining() bool {
+	return app.draining.Load()
+}
+
+func (app *Application) StartDraining() {
+	app.draining.Store(true)
+}
+
+// Register adds components to be started, in order, by Run. They are stopped
+// in reverse order, so servers should be registered after what they depend on.
+func (app *Application) Register(components ...Component) {
+	app.lifecycle.register(components...)
+}
+
+// Run starts all registered components and blocks until ctx is cancelled or a
+// component fails, then stops them, giving in-flight work the configured
+// shutdown wait to finish. A second SIGINT or SIGTERM cuts the drain delay
+// and the shutdown wait short.
+func (app *Application) Run(ctx context.Context) error {
+	failures := make(chan error, 1)
+	fail := func(err error) {
+		select {
+		case failures <- err:
+		default:
+		}
+	}
+
+	components, err := app.lifecycle.start(ctx, app.shutdownContext, fail)
+	if err != nil {
+		return err
+	}
+
+	var runErr error
+	select {
+	case <-ctx.Done():
+		log.Info().Msg("Received shutdown signal. Cleaning up...")
+	case runErr = <-failures:
+		log.Error().Err(runErr).Msg("Component failed. Shutting down...")
+	}
+
+	// the signals that cancelled ctx stay caught and no longer end the
+	// process, so another one forces the exit instead
+	forceCtx, stopForceSignal := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopForceSignal()
+	stopForceLog := context.AfterFunc(forceCtx, func() {
+		log.Warn().Msg("Received another shutdown signal. Stopping immediately...")
+	})
+	defer stopForceLog()
+
+	app.StartDraining()
+	if runErr == nil {
+		// give load balancers time to see /readyz failing and stop sending
+		// new requests before the listeners close
+		if delay := app.Config().ServerDrainDelay; delay > 0 {
+			log.Info().Msg(fmt.Sprintf("Draining for %v before stopping", delay))
+			timer := time.NewTimer(delay)
+			select {
+			case <-timer.C:
+			case <-forceCtx.Done():
+				timer.Stop()
+			}
+		}
+	}
+	stopCtx, cancel := app.shutdownContext()
+	defer cancel()
+	stopForceCancel := context.AfterFunc(forceCtx, cancel)
+	defer stopForceCancel()
+	return errors.Join(runErr, stop(stopCtx, components))
+}
+
+// RunTask starts the registered components, runs task and stops them again.
+// It gives one-off commands the same setup as the server.
+func (app *Application) RunTask(ctx context.Context, task func(ctx context.Context) error) error {
+	components, err := app.lifecycle.start(ctx, app.shutdownContext, func(err error) {
+		log.Error().Err(err).Msg("Component failed")
+	})
+	if err != nil {
+		return err
+	}
+
+	taskErr := task(ctx)
+	stopCtx, cancel := app.shutdownContext()
+	defer cancel()
+	return errors.Join(taskErr, stop(stopCtx, components))
+}
+
+func (app *Application) shutdownContext() (context.Context, context.CancelFunc) {
+	wait := app.Config().ServerShutdownWait
+	return context.WithTimeout(context.Background(), wait)
+}
+
+type databaseComponent struct {
+	db *sqlx.DB
+}
+
+func (c *databaseComponent) Name() string {
+	return "database"
+}
+
+func (c *databaseComponent) Start(ctx context.Context, fail func(error)) error {
+	if err := c.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	if c.db.DriverName() == "sqlite" {
+		return database.MigrateSQLite(ctx, c.db)
+	}
+	return nil
+}
+
+func (c *databaseComponent) Stop(ctx context.Context) error {
+	return c.db.Close()
+}
+
 func NewApplication(conf *config.Config) (*Application, error) {
-	db, err := sqlx.Connect("postgres", conf.PostgresConnStr)
+	if conf.Storage == "memory" {
+		return newMemoryApplication(conf)
+	}
+
+	db, err := database.Open(conf.DatabaseDSN())
 	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to connect to database")
+		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
-	repo := database.NewSQLRepository(db)
 
-	app := Application{
-		config:     conf,
-		repository: &repo,
+	var repo database.Repository
+	if db.DriverName() == "sqlite" {
+		repo = database.NewSQLiteRepository(db)
+	} else {
+		repo = database.NewSQLRepository(db)
 	}
-	return &app, nil
+
+	app := &Application{repository: &repo}
+	app.SetConfig(conf)
+	app.Register(&databaseComponent{db: db})
+	return app, nil
+}
+
+func newMemoryApplication(conf *config.Config) (*Application, error) {
+	memory := database.NewMemoryRepository()
+	if err := database.SeedMemoryRepository(context.Background(), memory); err != nil {
+		return nil, fmt.Errorf("failed to seed in-memory storage: %w", err)
+	}
+	log.Warn().Msg("using in-memory storage, data will be lost on shutdown")
+
+	var repo database.Repository = memory
+	app := &Application{repository: &repo}
+	app.SetConfig(conf)
+	return app, nil
 }
@@ -0,0 +1,151 @@
+package news
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Component is a part of the application with a managed lifetime. Start must
+// return once the component is up; failures happening afterwards are reported
+// through the fail callback, which makes the application shut down.
+type Component interface {
+	Name() string
+	Start(ctx context.Context, fail func(error)) error
+	Stop(ctx context.Context) error
+}
+
+type httpServerComponent struct {
+	name   string
+	server *http.Server
+}
+
+// NewHTTPServerComponent manages an http.Server, serving TLS when the server
+// has a TLSConfig. Stopping it drains in-flight requests until the stop
+// context expires, then closes the remaining connections.
+func NewHTTPServerComponent(name string, server *http.Server) Component {
+	return &httpServerComponent{name: name, server: server}
+}
+
+func (c *httpServerComponent) Name() string {
+	return c.name
+}
+
+func (c *httpServerComponent) Start(ctx context.Context, fail func(error)) error {
+	ln, err := net.Listen("tcp", c.server.Addr)
+	if err != nil {
+		return err
+	}
+
+	serve := c.server.Serve
+	scheme := "http"
+	if c.server.TLSConfig != nil {
+		scheme = "https"
+		serve = func(ln net.Listener) error { return c.server.ServeTLS(ln, "", "") }
+	}
+	log.Info().Msg(fmt.Sprintf("Starting %v server available at %v://%v", c.name, scheme, c.server.Addr))
+
+	go func() {
+		if err := serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fail(err)
+		}
+	}()
+	return nil
+}
+
+func (c *httpServerComponent) Stop(ctx context.Context) error {
+	if err := c.server.Shutdown(ctx); err != nil {
+		log.Warn().Err(err).Str("component", c.name).Msg("graceful shutdown timed out, closing connections")
+		return errors.Join(err, c.server.Close())
+	}
+	return nil
+}
+
+type workerComponent struct {
+	name string
+	run  func(ctx context.Context) error
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewWorker manages a background function. run is expected to block until its
+// context is cancelled; returning an error earlier shuts the application down.
+func NewWorker(name string, run func(ctx context.Context) error) Component {
+	return &workerComponent{name: name, run: run}
+}
+
+func (c *workerComponent) Name() string {
+	return c.name
+}
+
+func (c *workerComponent) Start(ctx context.Context, fail func(error)) error {
+	ctx, c.cancel = context.WithCancel(context.WithoutCancel(ctx))
+	c.done = make(chan struct{})
+
+	go func() {
+		defer close(c.done)
+		if err := c.run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			fail(err)
+		}
+	}()
+	return nil
+}
+
+func (c *workerComponent) Stop(ctx context.Context) error {
+	c.cancel()
+	select {
+	case <-c.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type lifecycle struct {
+	mu         sync.Mutex
+	components []Component
+}
+
+func (l *lifecycle) register(components ...Component) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.components = append(l.components, components...)
+}
+
+// start starts the components in registration order. If one fails, the ones
+// already started are stopped again before returning.
+func (l *lifecycle) start(ctx context.Context, stopCtx func() (context.Context, context.CancelFunc), fail func(error)) ([]Component, error) {
+	l.mu.Lock()
+	components := append([]Component(nil), l.components...)
+	l.mu.Unlock()
+
+	for i, c := range components {
+		log.Debug().Str("component", c.Name()).Msg("starting component")
+		if err := c.Start(ctx, fail); err != nil {
+			err = fmt.Errorf("starting %v failed: %w", c.Name(), err)
+			ctx, cancel := stopCtx()
+			defer cancel()
+			return nil, errors.Join(err, stop(ctx, components[:i]))
+		}
+	}
+	return components, nil
+}
+
+// stop stops the components in reverse order, continuing past failures.
+func stop(ctx context.Context, components []Component) error {
+	var errs []error
+	for i := len(components) - 1; i >= 0; i-- {
+		c := components[i]
+		log.Debug().Str("component", c.Name()).Msg("stopping component")
+		if err := c.Stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("stopping %v failed: %w", c.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,74 @@
+package recommend
+
+import (
+	"context"
+	"time"
+
+	"github.com/sunba23/news/internal/database"
+)
+
+// candidateWindow bounds how old recommended news can be.
+const candidateWindow = 30 * 24 * time.Hour
+
+// Recommender loads the scoring input from the repository.
+type Recommender struct {
+	repository     database.Repository
+	weights        Weights
+	relatedWeights RelatedWeights
+	now            func() time.Time
+}
+
+func NewRecommender(repository database.Repository) *Recommender {
+	return &Recommender{
+		repository:     repository,
+		weights:        DefaultWeights,
+		relatedWeights: DefaultRelatedWeights,
+		now:            time.Now,
+	}
+}
+
+// Recommend returns up to limit recommendations for the user, best first.
+func (r *Recommender) Recommend(ctx context.Context, userID string, limit int) ([]Recommendation, error) {
+	now := r.now().UTC()
+	in := Input{Now: now}
+
+	var err error
+	if in.FavoriteTags, err = r.repository.GetFavoriteTags(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	since := now.Add(-candidateWindow)
+	in.Candidates, err = r.repository.SearchNews(ctx, database.NewsFilter{Since: &since, ViewerID: userID})
+	if err != nil {
+		return nil, err
+	}
+
+	if in.Interactions, err = r.repository.GetInteractions(ctx, userID); err != nil {
+		return nil, err
+	}
+	historyIDs := make([]int, 0, len(in.Interactions))
+	for _, interaction := range in.Interactions {
+		historyIDs = append(historyIDs, interaction.NewsID)
+	}
+	history, err := r.repository.GetNewsByIDs(ctx, historyIDs)
+	if err != nil {
+		return nil, err
+	}
+	in.History = make(map[int]database.News, len(history))
+	for _, n := range history {
+		in.History[n.ID] = n
+	}
+
+	if in.TagNewsCounts, err = r.repository.GetTagNewsCounts(ctx); err != nil {
+		return nil, err
+	}
+	if in.Cooccurrence, err = r.repository.GetTagCooccurrence(ctx); err != nil {
+		return nil, err
+	}
+
+	recommendations := Score(in, r.weights)
+	if len(recommendations) > limit {
+		recommendations = recommendations[:limit]
+	}
+	return recommendations, nil
+}
@@ -0,0 +1,131 @@
+package recommend
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/sunba23/news/internal/database"
+	"github.com/sunba23/news/internal/similarity"
+)
+
+// relatedWindow bounds how far before the article related news are looked
+// for.
+const relatedWindow = 90 * 24 * time.Hour
+
+// RelatedWeights balance the related articles signals. The combined signal is
+// multiplied by a decay over the time between both articles, so coverage of
+// the same period ranks first.
+type RelatedWeights struct {
+	Tags     float64
+	Text     float64
+	HalfLife time.Duration
+	// Duplicate is the text similarity from which an article is considered a
+	// near-duplicate and left out.
+	Duplicate float64
+}
+
+var DefaultRelatedWeights = RelatedWeights{
+	Tags:      1.0,
+	Text:      1.5,
+	HalfLife:  7 * 24 * time.Hour,
+	Duplicate: 0.8,
+}
+
+type Related struct {
+	News       database.News  `json:"news"`
+	Score      float64        `json:"score"`
+	SharedTags []database.Tag `json:"shared_tags"`
+	Similarity float64        `json:"similarity"`
+}
+
+// RankRelated ranks the candidates by similarity to the article. The article
+// itself, near-duplicates of it and candidates sharing neither tags nor words
+// are left out. Ties are broken by recency and then id.
+func RankRelated(article database.News, candidates []database.News, w RelatedWeights) []Related {
+	documents := make([][]string, 0, len(candidates)+1)
+	documents = append(documents, newsTokens(article))
+	for _, n := range candidates {
+		documents = append(documents, newsTokens(n))
+	}
+	corpus := similarity.NewCorpus(documents)
+	target := corpus.Vector(documents[0])
+	targetTags := tagIDs(article.Tags)
+
+	result := make([]Related, 0)
+	for i, n := range candidates {
+		if n.ID == article.ID {
+			continue
+		}
+		text := similarity.Cosine(target, corpus.Vector(documents[i+1]))
+		if text >= w.Duplicate {
+			continue
+		}
+		tags := similarity.Jaccard(targetTags, tagIDs(n.Tags))
+		signal := w.Tags*tags + w.Text*text
+		if signal == 0 {
+			continue
+		}
+
+		shared := make([]database.Tag, 0)
+		for _, tag := range n.Tags {
+			if slices.Contains(targetTags, tag.ID) {
+				shared = append(shared, tag)
+			}
+		}
+		distance := n.CreatedAt.Sub(article.CreatedAt).Abs()
+		result = append(result, Related{
+			News:       n,
+			Score:      signal * decay(distance, w.HalfLife),
+			SharedTags: shared,
+			Similarity: text,
+		})
+	}
+
+	slices.SortFunc(result, func(a, b Related) int {
+		if c := cmp.Compare(b.Score, a.Score); c != 0 {
+			return c
+		}
+		if c := b.News.CreatedAt.Compare(a.News.CreatedAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.News.ID, a.News.ID)
+	})
+	return result
+}
+
+// Related returns up to limit articles related to the news with the given id,
+// or nil when it does not exist.
+func (r *Recommender) Related(ctx context.Context, newsID int, limit int) ([]Related, error) {
+	article, err := r.repository.GetNewsByID(ctx, newsID)
+	if err != nil || article == nil {
+		return nil, err
+	}
+
+	since := article.CreatedAt.Add(-relatedWindow)
+	candidates, err := r.repository.SearchNews(ctx, database.NewsFilter{Since: &since})
+	if err != nil {
+		return nil, err
+	}
+
+	related := RankRelated(*article, candidates, r.relatedWeights)
+	if len(related) > limit {
+		related = related[:limit]
+	}
+	return related, nil
+}
+
+// newsTokens counts the title twice, as it summarizes the article.
+func newsTokens(n database.News) []string {
+	title := similarity.Tokenize(n.Title)
+	return append(append(title, title...), similarity.Tokenize(n.Content)...)
+}
+
+func tagIDs(tags []database.Tag) []int {
+	ids := make([]int, 0, len(tags))
+	for _, tag := range tags {
+		ids = append(ids, tag.ID)
+	}
+	return ids
+}
@@ -0,0 +1,216 @@
+// Package recommend ranks news for a user from the tags they follow, the tags
+// of what they read and bookmarked, and how tags co-occur across all news.
+package recommend
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/sunba23/news/internal/database"
+)
+
+// Weights balance the scoring signals. The combined signal is multiplied by
+// an exponential recency decay with the given half-life.
+type Weights struct {
+	Favorite     float64
+	Cooccurrence float64
+	History      float64
+	Bookmark     float64
+	HalfLife     time.Duration
+}
+
+var DefaultWeights = Weights{
+	Favorite:     1.0,
+	Cooccurrence: 0.5,
+	History:      0.75,
+	Bookmark:     2.0,
+	HalfLife:     72 * time.Hour,
+}
+
+type ReasonKind string
+
+const (
+	ReasonFavorite     ReasonKind = "favorite"
+	ReasonCooccurrence ReasonKind = "related"
+	ReasonHistory      ReasonKind = "history"
+	ReasonBookmark     ReasonKind = "bookmark"
+)
+
+type Reason struct {
+	Kind    ReasonKind `json:"kind"`
+	Tag     string     `json:"tag"`
+	Message string     `json:"message"`
+}
+
+type Recommendation struct {
+	News    database.News `json:"news"`
+	Score   float64       `json:"score"`
+	Reasons []Reason      `json:"reasons"`
+}
+
+// Input is everything the scoring function looks at. History holds the news
+// the user interacted with, keyed by id, with their tags.
+type Input struct {
+	Now           time.Time
+	FavoriteTags  []database.Tag
+	Candidates    []database.News
+	Interactions  []database.Interaction
+	History       map[int]database.News
+	TagNewsCounts map[int]int
+	Cooccurrence  []database.TagPair
+}
+
+// Score ranks the candidates. It is deterministic: the same input always
+// yields the same order, ties being broken by recency and then id. Candidates
+// the user already interacted with or that match no signal are left out.
+func Score(in Input, w Weights) []Recommendation {
+	favorites := make(map[int]bool, len(in.FavoriteTags))
+	for _, tag := range in.FavoriteTags {
+		favorites[tag.ID] = true
+	}
+
+	profile, seen := historyProfile(in, w)
+	related := relatedToFavorites(in, favorites)
+
+	var result []Recommendation
+	for _, n := range in.Candidates {
+		if seen[n.ID] {
+			continue
+		}
+
+		var signal float64
+		var reasons []Reason
+		for _, tag := range n.Tags {
+			if favorites[tag.ID] {
+				signal += w.Favorite
+				reasons = append(reasons, Reason{
+					Kind:    ReasonFavorite,
+					Tag:     tag.Name,
+					Message: fmt.Sprintf("because you follow %v", tag.Name),
+				})
+				continue
+			}
+			if strength, ok := related[tag.ID]; ok {
+				signal += w.Cooccurrence * strength.value
+				reasons = append(reasons, Reason{
+					Kind:    ReasonCooccurrence,
+					Tag:     tag.Name,
+					Message: fmt.Sprintf("because %v often appears with %v, which you follow", tag.Name, strength.via),
+				})
+			}
+		}
+		for _, tag := range n.Tags {
+			weight, ok := profile[tag.ID]
+			if !ok {
+				continue
+			}
+			signal += w.History * weight.value
+			reason := Reason{
+				Kind:    ReasonHistory,
+				Tag:     tag.Name,
+				Message: fmt.Sprintf("because you read about %v", tag.Name),
+			}
+			if weight.bookmarked {
+				reason.Kind = ReasonBookmark
+				reason.Message = fmt.Sprintf("because you bookmarked news about %v", tag.Name)
+			}
+			reasons = append(reasons, reason)
+		}
+		if signal == 0 {
+			continue
+		}
+
+		result = append(result, Recommendation{
+			News:    n,
+			Score:   signal * decay(in.Now.Sub(n.CreatedAt), w.HalfLife),
+			Reasons: reasons,
+		})
+	}
+
+	slices.SortFunc(result, func(a, b Recommendation) int {
+		if c := cmp.Compare(b.Score, a.Score); c != 0 {
+			return c
+		}
+		if c := b.News.CreatedAt.Compare(a.News.CreatedAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.News.ID, a.News.ID)
+	})
+	return result
+}
+
+// decay halves the weight of an article every halfLife. Articles from the
+// future, e.g. due to clock skew, are not boosted.
+func decay(age, halfLife time.Duration) float64 {
+	if age <= 0 || halfLife <= 0 {
+		return 1
+	}
+	return math.Exp(-math.Ln2 * float64(age) / float64(halfLife))
+}
+
+// historyWeight is the weight of a tag in the user's history, and whether
+// the user bookmarked news with it rather than only read them.
+type historyWeight struct {
+	value      float64
+	bookmarked bool
+}
+
+// historyProfile weights tags by how often they appear in the news the user
+// read or bookmarked, normalized so the strongest tag weighs 1. It also
+// returns the ids of those news so they are not recommended again.
+func historyProfile(in Input, w Weights) (map[int]historyWeight, map[int]bool) {
+	seen := make(map[int]bool)
+	raw := make(map[int]historyWeight)
+	for _, interaction := range in.Interactions {
+		seen[interaction.NewsID] = true
+		bookmarked := interaction.Kind == database.InteractionBookmark
+		weight := 1.0
+		if bookmarked {
+			weight = w.Bookmark
+		}
+		for _, tag := range in.History[interaction.NewsID].Tags {
+			current := raw[tag.ID]
+			raw[tag.ID] = historyWeight{value: current.value + weight, bookmarked: current.bookmarked || bookmarked}
+		}
+	}
+
+	var top float64
+	for _, v := range raw {
+		top = max(top, v.value)
+	}
+	profile := make(map[int]historyWeight, len(raw))
+	for id, v := range raw {
+		profile[id] = historyWeight{value: v.value / top, bookmarked: v.bookmarked}
+	}
+	return profile, seen
+}
+
+type relatedStrength struct {
+	value float64
+	via   string
+}
+
+// relatedToFavorites scores non-favorite tags by the strongest conditional
+// probability of a favorite tag appearing on news carrying them. Pairs are
+// expected ordered by tag ids, so ties resolve to the lowest favorite id.
+func relatedToFavorites(in Input, favorites map[int]bool) map[int]relatedStrength {
+	names := make(map[int]string, len(in.FavoriteTags))
+	for _, tag := range in.FavoriteTags {
+		names[tag.ID] = tag.Name
+	}
+
+	related := make(map[int]relatedStrength)
+	for _, pair := range in.Cooccurrence {
+		if favorites[pair.TagID] || !favorites[pair.OtherTagID] || in.TagNewsCounts[pair.TagID] == 0 {
+			continue
+		}
+		value := float64(pair.Count) / float64(in.TagNewsCounts[pair.TagID])
+		if current, ok := related[pair.TagID]; !ok || value > current.value {
+			related[pair.TagID] = relatedStrength{value: value, via: names[pair.OtherTagID]}
+		}
+	}
+	return related
+}
@@ -0,0 +1,213 @@
+package recommend
+
+import (
+	"math"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/sunba23/news/internal/database"
+)
+
+var (
+	golang     = database.Tag{ID: 1, Name: "golang"}
+	docker     = database.Tag{ID: 2, Name: "docker"}
+	python     = database.Tag{ID: 3, Name: "python"}
+	kubernetes = database.Tag{ID: 4, Name: "kubernetes"}
+	rust       = database.Tag{ID: 5, Name: "rust"}
+)
+
+func newsItem(id int, createdAt time.Time, tags ...database.Tag) database.News {
+	return database.News{ID: id, Title: "news", CreatedAt: createdAt, Tags: tags}
+}
+
+type scored struct {
+	id      int
+	score   float64
+	reasons []ReasonKind
+}
+
+func TestScore(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	w := DefaultWeights
+
+	tests := []struct {
+		name string
+		in   Input
+		want []scored
+	}{
+		{
+			name: "favorite tag overlap",
+			in: Input{
+				Now:          now,
+				FavoriteTags: []database.Tag{golang, docker},
+				Candidates: []database.News{
+					newsItem(1, now, golang),
+					newsItem(2, now, golang, docker),
+					newsItem(3, now, rust),
+				},
+			},
+			// news matching no signal are left out
+			want: []scored{
+				{2, 2 * w.Favorite, []ReasonKind{ReasonFavorite, ReasonFavorite}},
+				{1, w.Favorite, []ReasonKind{ReasonFavorite}},
+			},
+		},
+		{
+			name: "co-occurrence with followed tags",
+			in: Input{
+				Now:          now,
+				FavoriteTags: []database.Tag{golang, docker},
+				Candidates: []database.News{
+					newsItem(1, now, python),
+					newsItem(2, now, kubernetes),
+				},
+				// golang appears on half the python news, docker on all the
+				// kubernetes news and golang on a quarter of them
+				TagNewsCounts: map[int]int{python.ID: 4, kubernetes.ID: 4},
+				Cooccurrence: []database.TagPair{
+					{TagID: python.ID, OtherTagID: golang.ID, Count: 2},
+					{TagID: kubernetes.ID, OtherTagID: golang.ID, Count: 1},
+					{TagID: kubernetes.ID, OtherTagID: docker.ID, Count: 4},
+				},
+			},
+			want: []scored{
+				{2, w.Cooccurrence * 1, []ReasonKind{ReasonCooccurrence}},
+				{1, w.Cooccurrence * 0.5, []ReasonKind{ReasonCooccurrence}},
+			},
+		},
+		{
+			name: "bookmarks weigh more than reads",
+			in: Input{
+				Now: now,
+				Candidates: []database.News{
+					newsItem(1, now, python),
+					newsItem(2, now, rust),
+					newsItem(10, now, python),
+				},
+				Interactions: []database.Interaction{
+					{NewsID: 10, Kind: database.InteractionRead},
+					{NewsID: 11, Kind: database.InteractionBookmark},
+				},
+				History: map[int]database.News{
+					10: newsItem(10, now, python),
+					11: newsItem(11, now, rust),
+				},
+			},
+			// rust weighs w.Bookmark against 1 for python, normalized to 1;
+			// news already read are not recommended again
+			want: []scored{
+				{2, w.History, []ReasonKind{ReasonBookmark}},
+				{1, w.History / w.Bookmark, []ReasonKind{ReasonHistory}},
+			},
+		},
+		{
+			name: "older news decay",
+			in: Input{
+				Now:          now,
+				FavoriteTags: []database.Tag{golang},
+				Candidates: []database.News{
+					newsItem(1, now.Add(-2*w.HalfLife), golang),
+					newsItem(2, now.Add(-w.HalfLife), golang),
+					newsItem(3, now, golang),
+				},
+			},
+			want: []scored{
+				{3, w.Favorite, []ReasonKind{ReasonFavorite}},
+				{2, w.Favorite / 2, []ReasonKind{ReasonFavorite}},
+				{1, w.Favorite / 4, []ReasonKind{ReasonFavorite}},
+			},
+		},
+		{
+			name: "ties are broken by recency then id",
+			in: Input{
+				Now:          now,
+				FavoriteTags: []database.Tag{golang},
+				// news from the future are not boosted, so all score the same
+				Candidates: []database.News{
+					newsItem(1, now, golang),
+					newsItem(3, now, golang),
+					newsItem(2, now.Add(time.Hour), golang),
+				},
+			},
+			want: []scored{
+				{2, w.Favorite, []ReasonKind{ReasonFavorite}},
+				{3, w.Favorite, []ReasonKind{ReasonFavorite}},
+				{1, w.Favorite, []ReasonKind{ReasonFavorite}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Score(tt.in, w)
+			assertScored(t, got, tt.want)
+
+			// the order of the candidates does not matter
+			reversed := tt.in
+			reversed.Candidates = slices.Clone(tt.in.Candidates)
+			slices.Reverse(reversed.Candidates)
+			assertScored(t, Score(reversed, w), tt.want)
+		})
+	}
+}
+
+func TestScoreReasons(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	in := Input{
+		Now:          now,
+		FavoriteTags: []database.Tag{golang},
+		Candidates:   []database.News{newsItem(1, now, golang, python, rust)},
+		Interactions: []database.Interaction{
+			{NewsID: 10, Kind: database.InteractionRead},
+			{NewsID: 11, Kind: database.InteractionBookmark},
+		},
+		History: map[int]database.News{
+			10: newsItem(10, now, python),
+			11: newsItem(11, now, rust),
+		},
+	}
+
+	got := Score(in, DefaultWeights)
+	if len(got) != 1 {
+		t.Fatalf("Score() returned %v recommendations, want 1", len(got))
+	}
+	want := []Reason{
+		{Kind: ReasonFavorite, Tag: "golang", Message: "because you follow golang"},
+		{Kind: ReasonHistory, Tag: "python", Message: "because you read about python"},
+		{Kind: ReasonBookmark, Tag: "rust", Message: "because you bookmarked news about rust"},
+	}
+	if !slices.Equal(got[0].Reasons, want) {
+		t.Errorf("Score() reasons = %+v, want %+v", got[0].Reasons, want)
+	}
+}
+
+func assertScored(t *testing.T, got []Recommendation, want []scored) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("Score() returned news %v, want %v", recommendationIDs(got), want)
+	}
+	for i, rec := range got {
+		if rec.News.ID != want[i].id {
+			t.Fatalf("Score() returned news %v, want %v", recommendationIDs(got), want)
+		}
+		if math.Abs(rec.Score-want[i].score) > 1e-9 {
+			t.Errorf("score of news %v = %v, want %v", rec.News.ID, rec.Score, want[i].score)
+		}
+		var kinds []ReasonKind
+		for _, reason := range rec.Reasons {
+			kinds = append(kinds, reason.Kind)
+		}
+		if !slices.Equal(kinds, want[i].reasons) {
+			t.Errorf("reasons of news %v = %v, want %v", rec.News.ID, kinds, want[i].reasons)
+		}
+	}
+}
+
+func recommendationIDs(recommendations []Recommendation) []int {
+	ids := make([]int, 0, len(recommendations))
+	for _, rec := range recommendations {
+		ids = append(ids, rec.News.ID)
+	}
+	return ids
+}
@@ -0,0 +1,113 @@
+// Package searches matches newly ingested news against the searches users
+// saved, recording the matches to notify them about.
+package searches
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sunba23/news/internal/database"
+)
+
+// batchSize is how many news are checked per repository round trip.
+const batchSize = 500
+
+// Matcher periodically checks the news not checked yet against every saved
+// search.
+type Matcher struct {
+	repository database.Repository
+	interval   time.Duration
+}
+
+func NewMatcher(repository database.Repository, interval time.Duration) *Matcher {
+	return &Matcher{repository: repository, interval: interval}
+}
+
+// Run matches new news until ctx is cancelled. Failures are logged and
+// retried on the next tick.
+func (m *Matcher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		matched, err := m.MatchPending(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Error().Err(err).Msg("matching news against saved searches failed")
+		} else if matched > 0 {
+			log.Debug().Int("matches", matched).Msg("matched news against saved searches")
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// MatchPending checks every saved search against the news not checked yet,
+// returning how many matches were recorded. News are tracked one by one
+// rather than by the highest id checked, as a fetcher transaction can commit
+// a lower id after a higher one was seen. The date window of a search does
+// not apply, as the news checked are new.
+func (m *Matcher) MatchPending(ctx context.Context) (int, error) {
+	var matched int
+	for {
+		pending, err := m.repository.GetUnsearchedNewsIDs(ctx, batchSize)
+		if err != nil {
+			return matched, err
+		}
+		if len(pending) == 0 {
+			return matched, nil
+		}
+		searches, err := m.repository.GetAllSavedSearches(ctx)
+		if err != nil {
+			return matched, err
+		}
+
+		for _, search := range searches {
+			n, err := m.match(ctx, search, pending)
+			if err != nil {
+				return matched, err
+			}
+			matched += n
+		}
+		if err := m.repository.MarkNewsSearched(ctx, pending); err != nil {
+			return matched, err
+		}
+		if len(pending) < batchSize {
+			return matched, nil
+		}
+	}
+}
+
+// match records which of the pending news, sorted by id, match the search,
+// skipping those ingested before it was saved.
+func (m *Matcher) match(ctx context.Context, search database.SavedSearch, pending []int) (int, error) {
+	filter := search.Filter(time.Now())
+	filter.Since = nil
+	filter.AfterID = max(search.LastNewsID, pending[0]-1)
+	filter.MaxID = pending[len(pending)-1]
+	if filter.AfterID >= filter.MaxID {
+		return 0, nil
+	}
+	filter.Fields = database.NewsFields{Columns: []string{"id"}, OmitTags: true}
+
+	news, err := m.repository.SearchNews(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	// the id range also holds news checked before
+	ids := make([]int, 0, len(news))
+	for _, n := range news {
+		if _, ok := slices.BinarySearch(pending, n.ID); ok {
+			ids = append(ids, n.ID)
+		}
+	}
+	if err := m.repository.AddSearchMatches(ctx, search.ID, ids); err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
@@ -0,0 +1,76 @@
+package searches
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/sunba23/news/internal/database"
+)
+
+func TestMatchPending(t *testing.T) {
+	ctx := context.Background()
+	repo := database.NewMemoryRepository()
+	matcher := NewMatcher(repo, time.Minute)
+
+	create := func(title string) int {
+		t.Helper()
+		n := database.News{Title: title, CreatedAt: time.Now().UTC()}
+		if err := repo.CreateNews(ctx, &n); err != nil {
+			t.Fatal(err)
+		}
+		return n.ID
+	}
+	matches := func(searchID int) []int {
+		t.Helper()
+		matches, err := repo.GetSearchMatches(ctx, searchID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids := make([]int, 0, len(matches))
+		for _, m := range matches {
+			ids = append(ids, m.NewsID)
+		}
+		slices.Sort(ids)
+		return ids
+	}
+	matchPending := func(want int) {
+		t.Helper()
+		matched, err := matcher.MatchPending(ctx)
+		if err != nil || matched != want {
+			t.Fatalf("MatchPending() = %v, %v, want %v, nil", matched, err, want)
+		}
+	}
+
+	user := database.User{ID: "user", GoogleID: "google", Email: "user@example.com"}
+	if err := repo.UpsertUser(ctx, &user); err != nil {
+		t.Fatal(err)
+	}
+	before := create("Go 1.25 released")
+	search := database.SavedSearch{UserID: user.ID, Name: "go", Query: "go"}
+	if err := repo.CreateSavedSearch(ctx, &search); err != nil {
+		t.Fatal(err)
+	}
+
+	late := create("Go generics explained")
+	create("Python 3.14 released")
+	seen := create("Go 1.26 released")
+	// a previous run saw the newest news item only, the lower ids committing
+	// after it
+	if err := repo.MarkNewsSearched(ctx, []int{seen}); err != nil {
+		t.Fatal(err)
+	}
+
+	matchPending(1)
+	if got, want := matches(search.ID), []int{late}; !slices.Equal(got, want) {
+		t.Errorf("matches = %v, want %v without news %v from before the search", got, want, before)
+	}
+
+	matchPending(0)
+	next := create("Go at scale")
+	matchPending(1)
+	if got, want := matches(search.ID), []int{late, next}; !slices.Equal(got, want) {
+		t.Errorf("matches = %v, want %v", got, want)
+	}
+}
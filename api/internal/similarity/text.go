@@ -0,0 +1,119 @@
+// Package similarity compares news by their tags and text.
+package similarity
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "by": true, "for": true, "from": true, "has": true, "have": true,
+	"how": true, "in": true, "is": true, "it": true, "its": true, "new": true,
+	"of": true, "on": true, "or": true, "that": true, "the": true, "this": true,
+	"to": true, "was": true, "were": true, "what": true, "will": true, "with": true,
+}
+
+// Tokenize lowercases the text and splits it into words, dropping stop words
+// and single characters.
+func Tokenize(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	tokens := words[:0]
+	for _, word := range words {
+		if len([]rune(word)) > 1 && !stopWords[word] {
+			tokens = append(tokens, word)
+		}
+	}
+	return tokens
+}
+
+// Vector is a sparse TF-IDF vector.
+type Vector map[string]float64
+
+// Corpus holds the document frequencies of a set of documents.
+type Corpus struct {
+	documents int
+	frequency map[string]int
+}
+
+// NewCorpus counts in how many of the tokenized documents each term appears.
+func NewCorpus(documents [][]string) *Corpus {
+	c := &Corpus{documents: len(documents), frequency: make(map[string]int)}
+	for _, tokens := range documents {
+		seen := make(map[string]bool, len(tokens))
+		for _, token := range tokens {
+			if !seen[token] {
+				seen[token] = true
+				c.frequency[token]++
+			}
+		}
+	}
+	return c
+}
+
+// Vector weighs the terms of a tokenized document by their frequency in it
+// and their smoothed inverse document frequency in the corpus.
+func (c *Corpus) Vector(tokens []string) Vector {
+	v := make(Vector, len(tokens))
+	for _, token := range tokens {
+		v[token]++
+	}
+	for token, count := range v {
+		idf := math.Log(float64(1+c.documents)/float64(1+c.frequency[token])) + 1
+		v[token] = count * idf
+	}
+	return v
+}
+
+// Cosine returns the cosine similarity of two vectors, 0 when either is empty.
+func Cosine(a, b Vector) float64 {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	var dot float64
+	for term, weight := range a {
+		dot += weight * b[term]
+	}
+	if dot == 0 {
+		return 0
+	}
+	return dot / (norm(a) * norm(b))
+}
+
+func norm(v Vector) float64 {
+	var sum float64
+	for _, weight := range v {
+		sum += weight * weight
+	}
+	return math.Sqrt(sum)
+}
+
+// Jaccard returns the size of the intersection of two id sets over the size
+// of their union, 0 when both are empty.
+func Jaccard(a, b []int) float64 {
+	set := make(map[int]bool, len(a))
+	for _, id := range a {
+		set[id] = true
+	}
+	union := len(set)
+	var shared int
+	seen := make(map[int]bool, len(b))
+	for _, id := range b {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if set[id] {
+			shared++
+		} else {
+			union++
+		}
+	}
+	if union == 0 {
+		return 0
+	}
+	return float64(shared) / float64(union)
+}
@@ -0,0 +1,111 @@
+package stories
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sunba23/news/internal/database"
+)
+
+const (
+	// window is how far apart in time near-duplicates may be published.
+	window = 72 * time.Hour
+	// batchSize is how many news are fingerprinted per repository round trip.
+	batchSize = 500
+)
+
+// Clusterer fingerprints news as they are ingested and assigns each to the
+// story cluster of its closest earlier near-duplicate, or to a new one.
+type Clusterer struct {
+	repository  database.Repository
+	interval    time.Duration
+	maxDistance int
+}
+
+// NewClusterer returns a Clusterer checking for new news every interval and
+// considering news whose fingerprints differ in at most maxDistance bits
+// near-duplicates.
+func NewClusterer(repository database.Repository, interval time.Duration, maxDistance int) *Clusterer {
+	return &Clusterer{
+		repository:  repository,
+		interval:    interval,
+		maxDistance: maxDistance,
+	}
+}
+
+// Run clusters new news until ctx is cancelled. Failures are logged and
+// retried on the next tick.
+func (c *Clusterer) Run(ctx context.Context) error {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		clustered, err := c.ClusterPending(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Error().Err(err).Msg("clustering news into stories failed")
+		} else if clustered > 0 {
+			log.Debug().Int("news", clustered).Msg("clustered news into stories")
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// ClusterPending fingerprints all news not clustered yet, returning how many
+// were.
+func (c *Clusterer) ClusterPending(ctx context.Context) (int, error) {
+	var clustered int
+	for {
+		pending, err := c.repository.GetUnfingerprintedNews(ctx, batchSize)
+		if err != nil {
+			return clustered, err
+		}
+		if len(pending) == 0 {
+			return clustered, nil
+		}
+
+		since := pending[0].CreatedAt.Add(-window)
+		until := pending[len(pending)-1].CreatedAt.Add(window)
+		known, err := c.repository.GetFingerprints(ctx, since, until)
+		if err != nil {
+			return clustered, err
+		}
+
+		for _, n := range pending {
+			fingerprint := database.Fingerprint{
+				NewsID:    n.ID,
+				SimHash:   int64(SimHash(n)),
+				CreatedAt: n.CreatedAt,
+			}
+			fingerprint.ClusterID = c.nearestCluster(fingerprint, known)
+			if err := c.repository.AddFingerprint(ctx, &fingerprint); err != nil {
+				return clustered, err
+			}
+			known = append(known, fingerprint)
+			clustered++
+		}
+		if len(pending) < batchSize {
+			return clustered, nil
+		}
+	}
+}
+
+// nearestCluster returns the cluster of the closest known fingerprint within
+// the time window, preferring the earliest on ties, or 0 when there is none.
+func (c *Clusterer) nearestCluster(fingerprint database.Fingerprint, known []database.Fingerprint) int {
+	best, bestDistance := 0, c.maxDistance+1
+	for _, other := range known {
+		if fingerprint.CreatedAt.Sub(other.CreatedAt).Abs() > window {
+			continue
+		}
+		if distance := Distance(uint64(fingerprint.SimHash), uint64(other.SimHash)); distance < bestDistance {
+			best, bestDistance = other.ClusterID, distance
+		}
+	}
+	return best
+}
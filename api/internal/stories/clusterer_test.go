@@ -0,0 +1,130 @@
+package stories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sunba23/news/internal/database"
+)
+
+func TestClusterPending(t *testing.T) {
+	ctx := context.Background()
+	repo := database.NewMemoryRepository()
+	clusterer := NewClusterer(repo, time.Minute, maxDistance)
+	t0 := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	create := func(n database.News, at time.Duration) int {
+		t.Helper()
+		n.CreatedAt = t0.Add(at)
+		if err := repo.CreateNews(ctx, &n); err != nil {
+			t.Fatal(err)
+		}
+		return n.ID
+	}
+	cluster := func(ids ...int) []int {
+		t.Helper()
+		memberships, err := repo.GetStoryMemberships(ctx, ids)
+		if err != nil {
+			t.Fatal(err)
+		}
+		clusters := make([]int, 0, len(ids))
+		for _, id := range ids {
+			membership, ok := memberships[id]
+			if !ok {
+				t.Fatalf("news %v was not clustered", id)
+			}
+			clusters = append(clusters, membership.ClusterID)
+		}
+		return clusters
+	}
+	cluster1 := func(id int) int {
+		t.Helper()
+		return cluster(id)[0]
+	}
+
+	first := create(goRelease, 0)
+	unrelated := create(pythonRelease, time.Hour)
+	// within the window of the first
+	retitled := create(goReleaseRetitled, 48*time.Hour)
+	// too late for the first, but within the window of retitled
+	reworded := create(goReleaseReworded, 100*time.Hour)
+	// too late for all of them
+	republished := create(goRelease, 200*time.Hour)
+
+	clustered, err := clusterer.ClusterPending(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clustered != 5 {
+		t.Errorf("ClusterPending() = %v, want 5", clustered)
+	}
+
+	story := cluster1(first)
+	for _, id := range []int{retitled, reworded} {
+		if got := cluster1(id); got != story {
+			t.Errorf("news %v is in cluster %v, want %v with news %v", id, got, story, first)
+		}
+	}
+	for _, id := range []int{unrelated, republished} {
+		if got := cluster1(id); got == story {
+			t.Errorf("news %v joined the cluster of news %v", id, first)
+		}
+	}
+	if cluster1(unrelated) == cluster1(republished) {
+		t.Errorf("news %v and %v share a cluster", unrelated, republished)
+	}
+
+	clustered, err = clusterer.ClusterPending(ctx)
+	if err != nil || clustered != 0 {
+		t.Errorf("ClusterPending() again = %v, %v, want 0, nil", clustered, err)
+	}
+
+	// news ingested later join the clusters of earlier runs
+	other := create(dockerModels, 201*time.Hour)
+	follow := create(goReleaseRetitled, 202*time.Hour)
+	clustered, err = clusterer.ClusterPending(ctx)
+	if err != nil || clustered != 2 {
+		t.Errorf("ClusterPending() of new news = %v, %v, want 2, nil", clustered, err)
+	}
+	if got, want := cluster1(follow), cluster1(republished); got != want {
+		t.Errorf("news %v is in cluster %v, want %v", follow, got, want)
+	}
+	for _, c := range cluster(first, unrelated, republished) {
+		if cluster1(other) == c {
+			t.Errorf("news %v joined an existing cluster", other)
+		}
+	}
+}
+
+func TestNearestCluster(t *testing.T) {
+	t0 := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	known := []database.Fingerprint{
+		{NewsID: 1, ClusterID: 1, SimHash: 0b0000, CreatedAt: t0},
+		{NewsID: 2, ClusterID: 2, SimHash: 0b0011, CreatedAt: t0.Add(time.Hour)},
+		{NewsID: 3, ClusterID: 3, SimHash: 0b0001, CreatedAt: t0.Add(100 * time.Hour)},
+	}
+	clusterer := NewClusterer(nil, time.Minute, 3)
+
+	tests := []struct {
+		name    string
+		simHash int64
+		at      time.Duration
+		want    int
+	}{
+		{"ties go to the earliest", 0b0001, 10 * time.Hour, 1},
+		{"the closest wins", 0b0111, 10 * time.Hour, 2},
+		{"fingerprints outside the window are ignored", 0b0000, 74 * time.Hour, 3},
+		{"the window bound is included", 0b0000, 72 * time.Hour, 1},
+		{"too distant for every cluster", 0b1111_0000, 10 * time.Hour, 0},
+		{"the maximum distance is included", 0b1110_0000, 10 * time.Hour, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fingerprint := database.Fingerprint{SimHash: tt.simHash, CreatedAt: t0.Add(tt.at)}
+			if got := clusterer.nearestCluster(fingerprint, known); got != tt.want {
+				t.Errorf("nearestCluster() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package stories
+
+import (
+	"context"
+
+	"github.com/sunba23/news/internal/database"
+)
+
+// Collapse keeps one news item per story cluster: the first of the cluster in
+// the list, i.e. the newest for the default sort. Kept items of clusters with
+// more than one news get their StoryID and RelatedCount set. News not
+// clustered yet are kept as they are.
+func Collapse(ctx context.Context, repository database.Repository, news []database.News) ([]database.News, error) {
+	ids := make([]int, 0, len(news))
+	for _, n := range news {
+		ids = append(ids, n.ID)
+	}
+	memberships, err := repository.GetStoryMemberships(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[int]bool)
+	collapsed := make([]database.News, 0, len(news))
+	for _, n := range news {
+		membership, ok := memberships[n.ID]
+		if ok {
+			if seen[membership.ClusterID] {
+				continue
+			}
+			seen[membership.ClusterID] = true
+			if membership.Size > 1 {
+				n.StoryID = membership.ClusterID
+				n.RelatedCount = membership.Size - 1
+			}
+		}
+		collapsed = append(collapsed, n)
+	}
+	return collapsed, nil
+}
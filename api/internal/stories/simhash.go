@@ -0,0 +1,43 @@
+// Package stories groups near-duplicate news, the same story ingested from
+// different outlets, into story clusters.
+package stories
+
+import (
+	"hash/fnv"
+	"math/bits"
+
+	"github.com/sunba23/news/internal/database"
+	"github.com/sunba23/news/internal/similarity"
+)
+
+// SimHash fingerprints the title and content of a news item. Similar texts get
+// fingerprints differing in few bits. Features are single words rather than
+// shingles, as news snippets are too short for shingles to overlap much.
+func SimHash(n database.News) uint64 {
+	var weights [64]int
+	for _, token := range similarity.Tokenize(n.Title + " " + n.Content) {
+		h := fnv.New64a()
+		h.Write([]byte(token))
+		sum := h.Sum64()
+		for bit := range weights {
+			if sum&(1<<bit) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+
+	var fingerprint uint64
+	for bit, weight := range weights {
+		if weight > 0 {
+			fingerprint |= 1 << bit
+		}
+	}
+	return fingerprint
+}
+
+// Distance is the number of bits in which two fingerprints differ.
+func Distance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
@@ -0,0 +1,80 @@
+package stories
+
+import (
+	"testing"
+
+	"github.com/sunba23/news/internal/database"
+)
+
+// maxDistance is the default STORIES_MAX_DISTANCE.
+const maxDistance = 6
+
+var (
+	goRelease = database.News{
+		Title:   "Go 1.25 released with a new garbage collector",
+		Content: "The Go team released version 1.25 today, shipping an experimental garbage collector that cuts pause times, faster JSON encoding and container aware GOMAXPROCS defaults for programs running in Kubernetes.",
+	}
+	goReleaseRetitled = database.News{
+		Title:   "Go 1.25 is out with a new garbage collector",
+		Content: goRelease.Content,
+	}
+	goReleaseReworded = database.News{
+		Title:   goRelease.Title,
+		Content: "The Go team released Go 1.25 on Tuesday, shipping an experimental garbage collector that cuts pause times, faster JSON encoding and container aware GOMAXPROCS defaults for programs running in Kubernetes.",
+	}
+	pythonRelease = database.News{
+		Title:   "Python 3.14 brings free threading",
+		Content: "The Python core developers published Python 3.14 with an officially supported free threaded build, template strings, deferred evaluation of annotations and a new interpreter that speeds up pure Python code.",
+	}
+	dockerModels = database.News{
+		Title:   "Docker Desktop adds a model runner",
+		Content: "Docker announced a model runner for Docker Desktop that pulls language models as OCI artifacts and serves them locally behind an OpenAI compatible API for development.",
+	}
+)
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b uint64
+		want int
+	}{
+		{0, 0, 0},
+		{0b1011, 0b0001, 2},
+		{0, ^uint64(0), 64},
+		{1 << 63, 1, 2},
+	}
+	for _, tt := range tests {
+		if got := Distance(tt.a, tt.b); got != tt.want {
+			t.Errorf("Distance(%b, %b) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestSimHash(t *testing.T) {
+	tests := []struct {
+		name          string
+		a, b          database.News
+		nearDuplicate bool
+	}{
+		{"same text", goRelease, goRelease, true},
+		{"case and punctuation are ignored", goRelease, database.News{
+			Title:   "GO 1.25 RELEASED WITH A NEW GARBAGE COLLECTOR!",
+			Content: goRelease.Content + "..",
+		}, true},
+		{"different title", goRelease, goReleaseRetitled, true},
+		{"reworded content", goRelease, goReleaseReworded, true},
+		{"unrelated stories", goRelease, pythonRelease, false},
+		{"other unrelated stories", pythonRelease, dockerModels, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			distance := Distance(SimHash(tt.a), SimHash(tt.b))
+			if tt.nearDuplicate && distance > maxDistance {
+				t.Errorf("distance = %v, want at most %v", distance, maxDistance)
+			}
+			// unrelated texts differ in about half of the bits
+			if !tt.nearDuplicate && distance < 4*maxDistance {
+				t.Errorf("distance = %v, want at least %v", distance, 4*maxDistance)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+// Package tagging infers the tags of news from their title and content using
+// per-tag keyword, alias and regular expression rules.
+package tagging
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sunba23/news/internal/database"
+)
+
+// Weights of a single match of each kind of rule. A match found only in the
+// content counts for contentFactor of it.
+const (
+	aliasWeight   = 0.9
+	patternWeight = 0.7
+	keywordWeight = 0.35
+	contentFactor = 2.0 / 3
+)
+
+//go:embed default_rules.json
+var defaultRules []byte
+
+// Rule describes how to recognize a tag. Aliases name the subject directly,
+// keywords merely hint at it and patterns are regular expressions. Matching is
+// case-insensitive; aliases and keywords only match whole words.
+type Rule struct {
+	Aliases  []string `json:"aliases"`
+	Keywords []string `json:"keywords"`
+	Patterns []string `json:"patterns"`
+}
+
+// Rules maps tag names to their rules.
+type Rules map[string]Rule
+
+// LoadRules reads rules from a JSON file, or returns the built-in rules when
+// path is empty.
+func LoadRules(path string) (Rules, error) {
+	data := defaultRules
+	if path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("failed to read tagging rules: %w", err)
+		}
+	}
+
+	var rules Rules
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse tagging rules: %w", err)
+	}
+	return rules, nil
+}
+
+// WithAliases returns the rules extended with the tag aliases stored in the
+// repository.
+func (r Rules) WithAliases(tags []database.Tag, aliases []database.TagAlias) Rules {
+	names := make(map[int]string, len(tags))
+	for _, tag := range tags {
+		names[tag.ID] = tag.Name
+	}
+	extended := maps.Clone(r)
+	if extended == nil {
+		extended = Rules{}
+	}
+	for _, alias := range aliases {
+		name, ok := names[alias.TagID]
+		if !ok {
+			continue
+		}
+		rule := extended[name]
+		rule.Aliases = append(slices.Clone(rule.Aliases), alias.Alias)
+		extended[name] = rule
+	}
+	return extended
+}
+
+type term struct {
+	re     *regexp.Regexp
+	weight float64
+}
+
+type matcher struct {
+	tagID int
+	terms []term
+}
+
+type Engine struct {
+	matchers      []matcher
+	minConfidence float64
+}
+
+// NewEngine compiles the rules for the given tags. Every tag name is an alias
+// of its tag, so tags without rules are still recognized by name. Rules for
+// unknown tags are ignored.
+func NewEngine(tags []database.Tag, rules Rules, minConfidence float64) (*Engine, error) {
+	known := make(map[string]bool, len(tags))
+	e := &Engine{minConfidence: minConfidence}
+	for _, tag := range tags {
+		known[strings.ToLower(tag.Name)] = true
+		rule := rules[tag.Name]
+
+		m := matcher{tagID: tag.ID}
+		for _, alias := range append([]string{tag.Name}, rule.Aliases...) {
+			m.terms = append(m.terms, term{re: wordRegexp(alias), weight: aliasWeight})
+		}
+		for _, keyword := range rule.Keywords {
+			m.terms = append(m.terms, term{re: wordRegexp(keyword), weight: keywordWeight})
+		}
+		for _, pattern := range rule.Patterns {
+			re, err := regexp.Compile("(?i)" + pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern %q for tag %v: %w", pattern, tag.Name, err)
+			}
+			m.terms = append(m.terms, term{re: re, weight: patternWeight})
+		}
+		e.matchers = append(e.matchers, m)
+	}
+
+	for name := range rules {
+		if !known[strings.ToLower(name)] {
+			log.Warn().Str("tag", name).Msg("ignoring tagging rules for unknown tag")
+		}
+	}
+	return e, nil
+}
+
+func wordRegexp(phrase string) *regexp.Regexp {
+	return regexp.MustCompile(`(?i)(?:^|[^\p{L}\p{N}])` + regexp.QuoteMeta(phrase) + `(?:$|[^\p{L}\p{N}])`)
+}
+
+// Tag returns the tags recognized in the news item with at least the minimum
+// confidence, ordered by tag id. Matches are combined as independent evidence:
+// the confidence is the probability that at least one of them is right.
+func (e *Engine) Tag(n database.News) []database.TagAssignment {
+	var assignments []database.TagAssignment
+	for _, m := range e.matchers {
+		miss := 1.0
+		for _, t := range m.terms {
+			switch {
+			case t.re.MatchString(n.Title):
+				miss *= 1 - t.weight
+			case t.re.MatchString(n.Content):
+				miss *= 1 - t.weight*contentFactor
+			}
+		}
+		confidence := math.Round((1-miss)*1000) / 1000
+		if confidence > 0 && confidence >= e.minConfidence {
+			assignments = append(assignments, database.TagAssignment{TagID: m.tagID, Confidence: confidence})
+		}
+	}
+	return assignments
+}
@@ -0,0 +1,190 @@
+package tagging
+
+import (
+	"math"
+	"testing"
+
+	"github.com/sunba23/news/internal/database"
+)
+
+var (
+	golang     = database.Tag{ID: 1, Name: "golang"}
+	kubernetes = database.Tag{ID: 2, Name: "kubernetes"}
+	docker     = database.Tag{ID: 3, Name: "docker"}
+	rust       = database.Tag{ID: 4, Name: "rust"}
+
+	testTags  = []database.Tag{golang, kubernetes, docker, rust}
+	testRules = Rules{
+		"golang": {
+			Aliases:  []string{"go"},
+			Keywords: []string{"goroutine"},
+			Patterns: []string{`\bgo ?1\.\d+`},
+		},
+		"kubernetes": {
+			Keywords: []string{"kubectl", "helm chart"},
+		},
+		// rust has no rules and is recognized by name only
+	}
+)
+
+func TestEngineTag(t *testing.T) {
+	tests := []struct {
+		name    string
+		aliases []database.TagAlias
+		min     float64
+		news    database.News
+		want    []database.TagAssignment
+	}{
+		{
+			name: "tag name in the title",
+			news: database.News{Title: "Rust in production"},
+			want: []database.TagAssignment{{TagID: rust.ID, Confidence: aliasWeight}},
+		},
+		{
+			name: "tag name in the content only",
+			news: database.News{Title: "Systems programming", Content: "Why we moved to Rust."},
+			want: []database.TagAssignment{{TagID: rust.ID, Confidence: 0.6}},
+		},
+		{
+			name: "alias",
+			news: database.News{Title: "What's new in Go"},
+			want: []database.TagAssignment{{TagID: golang.ID, Confidence: aliasWeight}},
+		},
+		{
+			name: "keyword",
+			news: database.News{Title: "KUBECTL tips"},
+			want: []database.TagAssignment{{TagID: kubernetes.ID, Confidence: keywordWeight}},
+		},
+		{
+			name: "keyword phrase",
+			news: database.News{Title: "Writing a Helm chart"},
+			want: []database.TagAssignment{{TagID: kubernetes.ID, Confidence: keywordWeight}},
+		},
+		{
+			name: "pattern",
+			news: database.News{Title: "Release notes", Content: "Changes since go1.24"},
+			want: []database.TagAssignment{{TagID: golang.ID, Confidence: 0.467}},
+		},
+		{
+			// 1 - (1-0.9) * (1-0.7) * (1-0.35*2/3)
+			name: "matches combine as independent evidence",
+			news: database.News{Title: "Go 1.25 released", Content: "Every goroutine gets faster."},
+			want: []database.TagAssignment{{TagID: golang.ID, Confidence: 0.977}},
+		},
+		{
+			name: "a term counts once even when it matches title and content",
+			news: database.News{Title: "Docker", Content: "docker, docker and more docker"},
+			want: []database.TagAssignment{{TagID: docker.ID, Confidence: aliasWeight}},
+		},
+		{
+			name: "several tags are ordered by id",
+			news: database.News{Title: "Docker and Go", Content: "Running kubectl in Rust"},
+			want: []database.TagAssignment{
+				{TagID: golang.ID, Confidence: aliasWeight},
+				{TagID: kubernetes.ID, Confidence: 0.233},
+				{TagID: docker.ID, Confidence: aliasWeight},
+				{TagID: rust.ID, Confidence: 0.6},
+			},
+		},
+		{
+			name: "terms only match whole words",
+			news: database.News{Title: "Google is good at going", Content: "A rustic dockerfile with kubectls"},
+		},
+		{
+			name: "punctuation and line ends are word boundaries",
+			news: database.News{Title: "(Go)", Content: "we use docker-compose\nand rust"},
+			want: []database.TagAssignment{
+				{TagID: golang.ID, Confidence: aliasWeight},
+				{TagID: docker.ID, Confidence: 0.6},
+				{TagID: rust.ID, Confidence: 0.6},
+			},
+		},
+		{
+			name: "letters of other scripts are part of words",
+			news: database.News{Title: "Goé and Rustß"},
+		},
+		{
+			name:    "aliases from the repository",
+			aliases: []database.TagAlias{{Alias: "k8s", TagID: kubernetes.ID}, {Alias: "orphan", TagID: 99}},
+			news:    database.News{Title: "K8s at scale", Content: "an orphan alias is ignored"},
+			want:    []database.TagAssignment{{TagID: kubernetes.ID, Confidence: aliasWeight}},
+		},
+		{
+			name: "aliases are not known without the repository",
+			news: database.News{Title: "K8s at scale"},
+		},
+		{
+			name: "matches below the minimum confidence are dropped",
+			min:  0.3,
+			news: database.News{Title: "Kubectl tips", Content: "a goroutine leak"},
+			want: []database.TagAssignment{{TagID: kubernetes.ID, Confidence: keywordWeight}},
+		},
+		{
+			name: "the minimum confidence is inclusive",
+			min:  0.6,
+			news: database.News{Content: "Rust"},
+			want: []database.TagAssignment{{TagID: rust.ID, Confidence: 0.6}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := testRules.WithAliases(testTags, tt.aliases)
+			engine, err := NewEngine(testTags, rules, tt.min)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := engine.Tag(tt.news)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Tag() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i].TagID != tt.want[i].TagID || math.Abs(got[i].Confidence-tt.want[i].Confidence) > 1e-9 {
+					t.Fatalf("Tag() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestWithAliasesKeepsRules(t *testing.T) {
+	extended := testRules.WithAliases(testTags, []database.TagAlias{{Alias: "golang-lang", TagID: golang.ID}})
+	if got := len(testRules["golang"].Aliases); got != 1 {
+		t.Errorf("WithAliases() changed the original rules: %v", testRules["golang"].Aliases)
+	}
+	if got := extended["golang"].Aliases; len(got) != 2 || got[1] != "golang-lang" {
+		t.Errorf("WithAliases() aliases = %v, want [go golang-lang]", got)
+	}
+
+	if extended := Rules(nil).WithAliases(testTags, []database.TagAlias{{Alias: "k8s", TagID: kubernetes.ID}}); len(extended["kubernetes"].Aliases) != 1 {
+		t.Errorf("WithAliases() on no rules = %v", extended)
+	}
+}
+
+func TestNewEngineRejectsInvalidPatterns(t *testing.T) {
+	rules := Rules{"golang": {Patterns: []string{"go("}}}
+	if _, err := NewEngine(testTags, rules, 0); err == nil {
+		t.Error("NewEngine() accepted an invalid pattern")
+	}
+}
+
+func TestDefaultRules(t *testing.T) {
+	rules, err := LoadRules("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) == 0 {
+		t.Fatal("LoadRules() returned no built-in rules")
+	}
+	tags := make([]database.Tag, 0, len(rules))
+	for name := range rules {
+		tags = append(tags, database.Tag{ID: len(tags) + 1, Name: name})
+	}
+	if _, err := NewEngine(tags, rules, 0); err != nil {
+		t.Errorf("the built-in rules do not compile: %v", err)
+	}
+
+	if _, err := LoadRules("testdata/missing.json"); err == nil {
+		t.Error("LoadRules() of a missing file succeeded")
+	}
+}
@@ -0,0 +1,123 @@
+package trends
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sunba23/news/internal/database"
+)
+
+// MaxNews is how many trending news are kept per window.
+const MaxNews = 100
+
+// Snapshot holds the trends of every window as of ComputedAt.
+type Snapshot struct {
+	ComputedAt time.Time
+	Tags       map[Window][]TagTrend
+	News       map[Window][]NewsTrend
+}
+
+// Tracker periodically recomputes the trends from the repository, so requests
+// are served from the latest snapshot instead of scanning the news.
+type Tracker struct {
+	repository database.Repository
+	interval   time.Duration
+	snapshot   atomic.Pointer[Snapshot]
+}
+
+func NewTracker(repository database.Repository, interval time.Duration) *Tracker {
+	return &Tracker{repository: repository, interval: interval}
+}
+
+// Snapshot returns the latest trends, or nil before they were first computed.
+func (t *Tracker) Snapshot() *Snapshot {
+	return t.snapshot.Load()
+}
+
+// Run refreshes the snapshot until ctx is cancelled. Failures are logged and
+// the previous snapshot is kept until the next tick.
+func (t *Tracker) Run(ctx context.Context) error {
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := t.Refresh(ctx); err != nil && ctx.Err() == nil {
+			log.Error().Err(err).Msg("refreshing trends failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (t *Tracker) Refresh(ctx context.Context) error {
+	now := time.Now().UTC()
+
+	tags, err := t.repository.GetAllTags(ctx)
+	if err != nil {
+		return err
+	}
+	favorites, err := t.repository.GetTagFavoriteCounts(ctx)
+	if err != nil {
+		return err
+	}
+	since := now.Add(-WindowWeek.Duration() - baselinePeriod)
+	news, err := t.repository.SearchNews(ctx, database.NewsFilter{
+		Since:  &since,
+		Fields: database.NewsFields{Columns: []string{"id", "created_at"}},
+	})
+	if err != nil {
+		return err
+	}
+
+	snapshot := &Snapshot{
+		ComputedAt: now,
+		Tags:       make(map[Window][]TagTrend, len(Windows)),
+		News:       make(map[Window][]NewsTrend, len(Windows)),
+	}
+	var ids []int
+	for _, w := range Windows {
+		interactions, err := t.repository.GetInteractionCounts(ctx, now.Add(-w.Duration()))
+		if err != nil {
+			return err
+		}
+		snapshot.Tags[w] = Tags(now, w, tags, news, favorites)
+		trending := News(now, w, news, snapshot.Tags[w], interactions)
+		if len(trending) > MaxNews {
+			trending = trending[:MaxNews]
+		}
+		snapshot.News[w] = trending
+		for _, trend := range trending {
+			ids = append(ids, trend.News.ID)
+		}
+	}
+
+	// The news were loaded with their ids and dates only; fill in the rest
+	// for the ones kept.
+	full, err := t.repository.GetNewsByIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+	byID := make(map[int]database.News, len(full))
+	for _, n := range full {
+		byID[n.ID] = n
+	}
+	for w, trending := range snapshot.News {
+		kept := trending[:0]
+		for _, trend := range trending {
+			if n, ok := byID[trend.News.ID]; ok {
+				trend.News = n
+				kept = append(kept, trend)
+			}
+		}
+		snapshot.News[w] = kept
+	}
+
+	t.snapshot.Store(snapshot)
+	return nil
+}
@@ -0,0 +1,173 @@
+// Package trends detects tags and news gaining attention by comparing recent
+// activity with a baseline.
+package trends
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/sunba23/news/internal/database"
+)
+
+// baselinePeriod is how much history before a window its activity is
+// compared with.
+const baselinePeriod = 28 * 24 * time.Hour
+
+type Window string
+
+const (
+	WindowHour    Window = "1h"
+	WindowDay     Window = "24h"
+	WindowWeek    Window = "7d"
+	DefaultWindow        = WindowDay
+)
+
+var Windows = []Window{WindowHour, WindowDay, WindowWeek}
+
+func (w Window) Duration() time.Duration {
+	switch w {
+	case WindowHour:
+		return time.Hour
+	case WindowWeek:
+		return 7 * 24 * time.Hour
+	default:
+		return 24 * time.Hour
+	}
+}
+
+func ParseWindow(value string) (Window, error) {
+	if value == "" {
+		return DefaultWindow, nil
+	}
+	for _, w := range Windows {
+		if string(w) == value {
+			return w, nil
+		}
+	}
+	return "", fmt.Errorf("invalid window %q, expected 1h, 24h or 7d", value)
+}
+
+type TagTrend struct {
+	Tag database.Tag `json:"tag"`
+	// Count is the number of news tagged with the tag in the window.
+	Count int `json:"count"`
+	// Baseline is the number of news expected in a window of that length,
+	// averaged over the baseline period.
+	Baseline float64 `json:"baseline"`
+	// Spike is Count relative to Baseline, smoothed so tags without a
+	// baseline do not dominate.
+	Spike     float64 `json:"spike"`
+	Favorites int     `json:"favorites"`
+}
+
+type NewsTrend struct {
+	News      database.News `json:"news"`
+	Score     float64       `json:"score"`
+	Reads     int           `json:"reads"`
+	Bookmarks int           `json:"bookmarks"`
+}
+
+// Tags computes the trend of every tag used in the window, from news covering
+// at least the window and the baseline period before it. Tags are ordered by
+// spike, then count, then id.
+func Tags(now time.Time, w Window, tags []database.Tag, news []database.News, favorites map[int]int) []TagTrend {
+	start := now.Add(-w.Duration())
+	baselineStart := start.Add(-baselinePeriod)
+
+	counts := make(map[int]int)
+	baselineCounts := make(map[int]int)
+	for _, n := range news {
+		for _, tag := range n.Tags {
+			switch {
+			case !n.CreatedAt.Before(start) && !n.CreatedAt.After(now):
+				counts[tag.ID]++
+			case !n.CreatedAt.Before(baselineStart) && n.CreatedAt.Before(start):
+				baselineCounts[tag.ID]++
+			}
+		}
+	}
+
+	windowsInBaseline := float64(baselinePeriod) / float64(w.Duration())
+	trends := make([]TagTrend, 0)
+	for _, tag := range tags {
+		if counts[tag.ID] == 0 {
+			continue
+		}
+		baseline := float64(baselineCounts[tag.ID]) / windowsInBaseline
+		trends = append(trends, TagTrend{
+			Tag:       tag,
+			Count:     counts[tag.ID],
+			Baseline:  baseline,
+			Spike:     (float64(counts[tag.ID]) + 1) / (baseline + 1),
+			Favorites: favorites[tag.ID],
+		})
+	}
+
+	slices.SortFunc(trends, func(a, b TagTrend) int {
+		if c := cmp.Compare(b.Spike, a.Spike); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(b.Count, a.Count); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Tag.ID, b.Tag.ID)
+	})
+	return trends
+}
+
+// News ranks the news published in the window by the interactions recorded in
+// it, a bookmark counting twice as much as a read, multiplied by the mean
+// spike of their tags. Ties are broken by recency and then id.
+func News(now time.Time, w Window, news []database.News, tagTrends []TagTrend, interactions []database.InteractionCount) []NewsTrend {
+	start := now.Add(-w.Duration())
+
+	spikes := make(map[int]float64, len(tagTrends))
+	for _, t := range tagTrends {
+		spikes[t.Tag.ID] = t.Spike
+	}
+	reads := make(map[int]int)
+	bookmarks := make(map[int]int)
+	for _, i := range interactions {
+		switch i.Kind {
+		case database.InteractionRead:
+			reads[i.NewsID] += i.Count
+		case database.InteractionBookmark:
+			bookmarks[i.NewsID] += i.Count
+		}
+	}
+
+	trends := make([]NewsTrend, 0)
+	for _, n := range news {
+		if n.CreatedAt.Before(start) || n.CreatedAt.After(now) {
+			continue
+		}
+		spike := 1.0
+		if len(n.Tags) > 0 {
+			var sum float64
+			for _, tag := range n.Tags {
+				sum += spikes[tag.ID]
+			}
+			spike = sum / float64(len(n.Tags))
+		}
+		engagement := 1 + float64(reads[n.ID]) + 2*float64(bookmarks[n.ID])
+		trends = append(trends, NewsTrend{
+			News:      n,
+			Score:     engagement * spike,
+			Reads:     reads[n.ID],
+			Bookmarks: bookmarks[n.ID],
+		})
+	}
+
+	slices.SortFunc(trends, func(a, b NewsTrend) int {
+		if c := cmp.Compare(b.Score, a.Score); c != 0 {
+			return c
+		}
+		if c := b.News.CreatedAt.Compare(a.News.CreatedAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.News.ID, a.News.ID)
+	})
+	return trends
+}
@@ -55,7 +55,10 @@ class NewsApiClient(ApiClient):
             content = news.get("content", "") or news.get("description", "")
             author = news.get("author", "") or "Unknown"
             url = news.get("url", "")
+            source = (news.get("source") or {}).get("name") or ""
 
-            news_item = News(title=title, content=content, author=author, url=url)
+            news_item = News(
+                title=title, content=content, author=author, url=url, source=source
+            )
             news_list.append(news_item)
         return news_list
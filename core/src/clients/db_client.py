@@ -60,8 +60,8 @@ class PgClient(DbClient):
             query = SQL(
                 """
                 WITH inserted_news AS (
-                    INSERT INTO news (title, content, author, created_at)
-                    VALUES ({title}, {content}, {author}, CURRENT_TIMESTAMP)
+                    INSERT INTO news (title, content, author, source, created_at)
+                    VALUES ({title}, {content}, {author}, {source}, CURRENT_TIMESTAMP)
                     RETURNING id
                 ),
                 matched_tags AS (
@@ -78,6 +78,7 @@ class PgClient(DbClient):
                 title=Literal(article.title),
                 content=Literal(article.content),
                 author=Literal(article.author),
+                source=Literal(article.source),
                 tags=Literal(article.tags),
             )
 
@@ -5,10 +5,17 @@ class Tag:
 
 class News:
     def __init__(
-        self, title: str, content: str, author: str, url: str, tags: list[str] = None
+        self,
+        title: str,
+        content: str,
+        author: str,
+        url: str,
+        source: str = "",
+        tags: list[str] = None,
     ) -> None:
         self.title = title
         self.content = content
         self.author = author
         self.url = url
+        self.source = source
         self.tags = tags or []
@@ -2,19 +2,29 @@ BEGIN;
 
 TRUNCATE TABLE news_tags, news, tags RESTART IDENTITY CASCADE;
 
-INSERT INTO tags (name) VALUES 
-('golang'), 
-('python'), 
-('javascript'),
-('rust'),
-('docker'),
-('kubernetes'),
-('webdev'),
-('datascience'),
-('machinelearning'),
-('algorithms')
+INSERT INTO tags (name, slug, description) VALUES
+('golang', 'golang', 'The Go programming language'),
+('python', 'python', 'The Python programming language'),
+('javascript', 'javascript', 'JavaScript and its ecosystem'),
+('rust', 'rust', 'The Rust programming language'),
+('docker', 'docker', 'Containers and Docker tooling'),
+('kubernetes', 'kubernetes', 'Container orchestration with Kubernetes'),
+('webdev', 'webdev', 'Building for the web'),
+('datascience', 'datascience', 'Data analysis and visualisation'),
+('machinelearning', 'machinelearning', 'Machine learning models and tools'),
+('algorithms', 'algorithms', 'Algorithms and data structures'),
+('devops', 'devops', 'Deploying and operating software')
 ON CONFLICT (name) DO NOTHING;
 
+UPDATE tags SET parent_id = (SELECT id FROM tags WHERE name = 'devops') WHERE name IN ('docker', 'kubernetes');
+UPDATE tags SET parent_id = (SELECT id FROM tags WHERE name = 'datascience') WHERE name = 'machinelearning';
+
+INSERT INTO tag_aliases (alias, tag_id) VALUES
+('go', (SELECT id FROM tags WHERE name = 'golang')),
+('js', (SELECT id FROM tags WHERE name = 'javascript')),
+('k8s', (SELECT id FROM tags WHERE name = 'kubernetes')),
+('ml', (SELECT id FROM tags WHERE name = 'machinelearning'));
+
 INSERT INTO news (title, content, author, created_at) VALUES
 ('Go 1.21 Released', 'The latest version of Go introduces new features including...', 'Gopher', NOW() - interval '2 days'),
 ('Python 3.12 Performance Improvements', 'Significant speed boosts reported in the new Python release...', 'PyDev', NOW() - interval '5 days'),
@@ -0,0 +1,9 @@
+CREATE TABLE user_news_interactions (
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    news_id INT REFERENCES news(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL CHECK (kind IN ('read', 'bookmark')),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, news_id, kind)
+);
+
+INSERT INTO schema_migrations (version) VALUES (3) ON CONFLICT DO NOTHING;
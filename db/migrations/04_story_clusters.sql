@@ -0,0 +1,15 @@
+CREATE TABLE story_clusters (
+    id SERIAL PRIMARY KEY,
+    representative_id INT NOT NULL REFERENCES news(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE news_fingerprints (
+    news_id INT PRIMARY KEY REFERENCES news(id) ON DELETE CASCADE,
+    simhash BIGINT NOT NULL,
+    cluster_id INT NOT NULL REFERENCES story_clusters(id) ON DELETE CASCADE
+);
+
+CREATE INDEX news_fingerprints_cluster_id_idx ON news_fingerprints (cluster_id);
+
+INSERT INTO schema_migrations (version) VALUES (4) ON CONFLICT DO NOTHING;
@@ -0,0 +1,3 @@
+ALTER TABLE news_tags ADD COLUMN confidence REAL NOT NULL DEFAULT 1;
+
+INSERT INTO schema_migrations (version) VALUES (5) ON CONFLICT DO NOTHING;
@@ -0,0 +1,10 @@
+ALTER TABLE tags ADD COLUMN parent_id INT REFERENCES tags(id) ON DELETE SET NULL;
+
+CREATE TABLE tag_aliases (
+    alias TEXT PRIMARY KEY,
+    tag_id INT NOT NULL REFERENCES tags(id) ON DELETE CASCADE
+);
+
+CREATE INDEX tags_parent_id_idx ON tags (parent_id);
+
+INSERT INTO schema_migrations (version) VALUES (6) ON CONFLICT DO NOTHING;
@@ -0,0 +1,10 @@
+ALTER TABLE tags ADD COLUMN slug TEXT NOT NULL DEFAULT '';
+ALTER TABLE tags ADD COLUMN description TEXT NOT NULL DEFAULT '';
+
+UPDATE tags SET slug = TRIM(BOTH '-' FROM REGEXP_REPLACE(LOWER(name), '[^a-z0-9]+', '-', 'g'));
+
+ALTER TABLE tags ALTER COLUMN slug DROP DEFAULT;
+
+CREATE UNIQUE INDEX tags_slug_idx ON tags (slug);
+
+INSERT INTO schema_migrations (version) VALUES (7) ON CONFLICT DO NOTHING;
@@ -0,0 +1,27 @@
+CREATE TABLE saved_searches (
+    id SERIAL PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    query TEXT NOT NULL DEFAULT '',
+    match_all_tags BOOLEAN NOT NULL DEFAULT FALSE,
+    window_days INT NOT NULL DEFAULT 0,
+    last_news_id INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE saved_search_tags (
+    search_id INT REFERENCES saved_searches(id) ON DELETE CASCADE,
+    tag_id INT REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (search_id, tag_id)
+);
+
+CREATE TABLE saved_search_matches (
+    search_id INT REFERENCES saved_searches(id) ON DELETE CASCADE,
+    news_id INT REFERENCES news(id) ON DELETE CASCADE,
+    matched_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (search_id, news_id)
+);
+
+CREATE INDEX saved_searches_user_id_idx ON saved_searches (user_id);
+
+INSERT INTO schema_migrations (version) VALUES (9) ON CONFLICT DO NOTHING;
@@ -0,0 +1,3 @@
+ALTER TABLE users ADD COLUMN preferences JSONB NOT NULL DEFAULT '{}';
+
+INSERT INTO schema_migrations (version) VALUES (10) ON CONFLICT DO NOTHING;
@@ -0,0 +1,15 @@
+ALTER TABLE users ADD COLUMN deletion_requested_at TIMESTAMP;
+
+-- account_deletions records deletion requests. It has no foreign key to users
+-- so the record of a purged account outlives it.
+CREATE TABLE account_deletions (
+    id SERIAL PRIMARY KEY,
+    user_id UUID NOT NULL,
+    requested_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    cancelled_at TIMESTAMP,
+    purged_at TIMESTAMP
+);
+
+CREATE INDEX account_deletions_user_id_idx ON account_deletions (user_id);
+
+INSERT INTO schema_migrations (version) VALUES (11) ON CONFLICT DO NOTHING;
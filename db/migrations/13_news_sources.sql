@@ -0,0 +1,8 @@
+-- source is the publication a news item comes from, e.g. "The Verge".
+ALTER TABLE news ADD COLUMN source TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE user_muted_terms DROP CONSTRAINT user_muted_terms_kind_check;
+ALTER TABLE user_muted_terms ADD CONSTRAINT user_muted_terms_kind_check
+    CHECK (kind IN ('author', 'keyword', 'source'));
+
+INSERT INTO schema_migrations (version) VALUES (13) ON CONFLICT DO NOTHING;